@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 
 	"github.com/jamesroutley/sketch/sketch/printer"
 	"github.com/jamesroutley/sketch/sketch/reader"
+	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/spf13/cobra"
 )
 
@@ -42,12 +44,7 @@ By default, Sketchfmt prints the formatted file to stdout. You can supply the
 	Run: func(cmd *cobra.Command, args []string) {
 		// Read from stdin
 		if len(args) == 0 {
-			data, err := ioutil.ReadAll(os.Stdin)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			ast, err := reader.ReadWithoutReaderMacros(fmt.Sprintf("(do %s)", data))
+			ast, err := readForFormatting(os.Stdin, "")
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -57,12 +54,12 @@ By default, Sketchfmt prints the formatted file to stdout. You can supply the
 		}
 
 		for _, filename := range args {
-			data, err := ioutil.ReadFile(filename)
+			f, err := os.Open(filename)
 			if err != nil {
 				log.Fatal(err)
 			}
-
-			ast, err := reader.ReadWithoutReaderMacros(fmt.Sprintf("(do %s)", data))
+			ast, err := readForFormatting(f, filename)
+			f.Close()
 			if err != nil {
 				log.Fatal(err)
 			}
@@ -86,6 +83,39 @@ By default, Sketchfmt prints the formatted file to stdout. You can supply the
 	},
 }
 
+// readForFormatting reads every top level form from `r`, along with the
+// comments between them, without expanding reader macros. They're returned
+// wrapped in a `do` form, which is what printer.PrettyPrintTopLevelDo expects.
+func readForFormatting(r io.Reader, filename string) (types.SketchType, error) {
+	decoder := reader.NewDecoder(r, filename)
+	forms := []types.SketchType{&types.SketchSymbol{Value: "do"}}
+	var errs reader.ParseErrors
+	for {
+		form, err := decoder.DecodeRaw()
+		for _, comment := range decoder.Trivia() {
+			forms = append(forms, comment)
+		}
+		if err == io.EOF {
+			break
+		}
+		if parseErrs, ok := err.(reader.ParseErrors); ok {
+			errs = append(errs, parseErrs...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		forms = append(forms, form)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &types.SketchList{
+		List: types.NewList(forms),
+	}, nil
+}
+
 func init() {
 	rootCmd.AddCommand(formatCmd)
 
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"log"
+	"os"
 
 	"github.com/jamesroutley/sketch/sketch"
 	"github.com/spf13/cobra"
@@ -11,9 +12,26 @@ import (
 var replCmd = &cobra.Command{
 	Use:   "repl",
 	Short: "Launches the Sketch REPL",
-	// Long: ``,
+	Long: `Launches the Sketch REPL
+
+The REPL's history is saved to $XDG_STATE_HOME/sketch/history, or
+~/.sketchhistory if $XDG_STATE_HOME isn't set. This can be changed with the
+--history-file flag, or the SKETCH_HISTORY_FILE environment variable.
+
+Type :help in the REPL to list the commands it supports, such as :doc.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := sketch.Repl(); err != nil {
+		historyFile, err := cmd.Flags().GetString("history-file")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !cmd.Flags().Changed("history-file") {
+			historyFile = sketch.DefaultHistoryFile()
+			if env, ok := os.LookupEnv("SKETCH_HISTORY_FILE"); ok {
+				historyFile = env
+			}
+		}
+
+		if err := sketch.Repl(historyFile); err != nil {
 			log.Fatal(err)
 		}
 	},
@@ -21,4 +39,6 @@ var replCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(replCmd)
+
+	replCmd.Flags().String("history-file", "", "The file to save the REPL's history to. If empty, history isn't saved")
 }
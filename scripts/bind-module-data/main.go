@@ -79,7 +79,7 @@ func bindModuleData(module string) error {
 
 		source := &Source{
 			Filename: filepath.Base(sketchFile),
-			Code:     strings.TrimSpace(string(data)),
+			Code:     escapeBackticks(strings.TrimSpace(string(data))),
 		}
 
 		sources = append(sources, source)
@@ -110,3 +110,9 @@ func bindModuleData(module string) error {
 
 	return nil
 }
+
+// escapeBackticks escapes the backticks in `code` so it can be embedded in a
+// Go raw string literal. Sketch code uses backticks for quasiquoting.
+func escapeBackticks(code string) string {
+	return strings.ReplaceAll(code, "`", "` + \"`\" + `")
+}
@@ -1,108 +1,226 @@
 package core
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jamesroutley/sketch/sketch/limits"
 	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/jamesroutley/sketch/sketch/validation"
-	"golang.org/x/sync/errgroup"
 )
 
-// sketchMap implements map - i.e. run func for all items in a list
-func sketchMap(args ...types.SketchType) (types.SketchType, error) {
-	function, err := validation.FunctionArg("map", args[0], 0)
+// callFunc calls `function` with each of `items`, and returns the results in
+// the same order.
+type callFunc func(
+	ctx context.Context, function *types.SketchFunction, items []types.SketchType,
+) ([]types.SketchType, error)
+
+// sketchMap implements map - i.e. run func for all items in a list or vector.
+// Mapping over a vector returns a vector. The function is called on one item
+// at a time, in order - use pmap to call it in parallel.
+// > (map (fn (x) (* x 2)) (list 1 2 3))
+// (2 4 6)
+func sketchMap(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	return mapWith(ctx, "map", callSequentially, args...)
+}
+
+// pmap is like map, but calls the function on several items in parallel. It
+// uses at most as many goroutines as the evaluation's parallelism, which can
+// be set with with-parallelism. If the function fails for more than one item,
+// the error for the first of them is returned.
+// > (pmap (fn (x) (* x 2)) (list 1 2 3))
+// (2 4 6)
+func pmap(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	return mapWith(ctx, "pmap", callInParallel, args...)
+}
+
+func mapWith(ctx context.Context, fnName string, call callFunc, args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs(fnName, 2, args); err != nil {
+		return nil, err
+	}
+	function, err := validation.FunctionArg(fnName, args[0], 0)
 	if err != nil {
 		return nil, err
 	}
-	list, err := validation.ListArg("map", args[1], 1)
+	items, err := validation.SequenceArg(fnName, args[1], 1)
 	if err != nil {
 		return nil, err
 	}
 
-	items := list.List.ToSlice()
-
 	// Short circuit
 	if len(items) == 0 {
-		return list, nil
+		return args[1], nil
 	}
-
-	g := new(errgroup.Group)
-	mappedItems := make([]types.SketchType, len(items))
-	for i, item := range items {
-		i := i
-		item := item
-		g.Go(func() error {
-			mappedItem, err := function.Func(item)
-			if err != nil {
-				return err
-			}
-			mappedItems[i] = mappedItem
-			return nil
-		})
+	if err := allocateSequenceLike(ctx, args[1], len(items)); err != nil {
+		return nil, err
 	}
-	if err := g.Wait(); err != nil {
+
+	mappedItems, err := call(ctx, function, items)
+	if err != nil {
 		return nil, err
 	}
+	return types.NewSequenceLike(args[1], mappedItems), nil
+}
 
-	return &types.SketchList{
-		List: types.NewList(mappedItems),
-	}, nil
+// filter returns the items in a list or vector for which the function returns
+// a truthy value. The function is called on one item at a time, in order - use
+// pfilter to call it in parallel.
+// > (filter (fn (x) (> x 1)) (list 1 2 3))
+// (2 3)
+func filter(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	return filterWith(ctx, "filter", callSequentially, args...)
 }
 
-func filter(args ...types.SketchType) (types.SketchType, error) {
-	function, err := validation.FunctionArg("filter", args[0], 0)
+// pfilter is like filter, but calls the function on several items in
+// parallel, like pmap.
+// > (pfilter (fn (x) (> x 1)) (list 1 2 3))
+// (2 3)
+func pfilter(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	return filterWith(ctx, "pfilter", callInParallel, args...)
+}
+
+func filterWith(ctx context.Context, fnName string, call callFunc, args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs(fnName, 2, args); err != nil {
+		return nil, err
+	}
+	function, err := validation.FunctionArg(fnName, args[0], 0)
 	if err != nil {
 		return nil, err
 	}
-	list, err := validation.ListArg("filter", args[1], 1)
+	items, err := validation.SequenceArg(fnName, args[1], 1)
 	if err != nil {
 		return nil, err
 	}
 
-	items := list.List.ToSlice()
-
 	// Short circuit
 	if len(items) == 0 {
-		return list, nil
+		return args[1], nil
 	}
 
-	g := new(errgroup.Group)
-	filteredItems := make([]types.SketchType, len(items))
-	for i, item := range items {
-		i := i
-		item := item
-		g.Go(func() error {
-			passed, err := function.Func(item)
-			if err != nil {
-				return err
-			}
-			// Only add item to the filtered array if it's passed the filter
-			// function. We add it back to it's original position, and we'll
-			// filter out nil values in the array later. We do this to preserve
-			// the ordering of the list
-			if IsTruthy(passed) {
-				filteredItems[i] = item
-			}
+	passed, err := call(ctx, function, items)
+	if err != nil {
+		return nil, err
+	}
 
-			return nil
-		})
+	filtered := make([]types.SketchType, 0, len(items))
+	for i, item := range items {
+		if IsTruthy(passed[i]) {
+			filtered = append(filtered, item)
+		}
 	}
-	if err := g.Wait(); err != nil {
+	if err := allocateSequenceLike(ctx, args[1], len(filtered)); err != nil {
 		return nil, err
 	}
 
-	filtered := make([]types.SketchType, 0, len(items))
-	for _, item := range filteredItems {
-		if item == nil {
-			continue
+	return types.NewSequenceLike(args[1], filtered), nil
+}
+
+// callSequentially calls `function` with each of `items` in turn, stopping at
+// the first error.
+func callSequentially(
+	ctx context.Context, function *types.SketchFunction, items []types.SketchType,
+) ([]types.SketchType, error) {
+	results := make([]types.SketchType, len(items))
+	for i, item := range items {
+		result, err := function.Call(ctx, item)
+		if err != nil {
+			return nil, err
 		}
-		filtered = append(filtered, item)
+		results[i] = result
 	}
+	return results, nil
+}
 
-	return &types.SketchList{
-		List: types.NewList(filtered),
-	}, nil
+// callInParallel calls `function` with each of `items`, using a pool of at
+// most limits.Parallelism(ctx) goroutines. Each goroutine takes the next item
+// which hasn't been started yet. If any calls fail, the error for the lowest
+// index is returned, so the error doesn't depend on how the calls were
+// scheduled. Items after a failed one aren't started.
+func callInParallel(
+	ctx context.Context, function *types.SketchFunction, items []types.SketchType,
+) ([]types.SketchType, error) {
+	results := make([]types.SketchType, len(items))
+	errs := make([]error, len(items))
+
+	// next is the index of the next item to start, and firstFailure the
+	// lowest index whose call has failed so far
+	var next int64
+	firstFailure := int64(len(items))
+
+	workers := limits.Parallelism(ctx)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := limits.Fork(ctx)
+			for {
+				i := atomic.AddInt64(&next, 1) - 1
+				if i >= int64(len(items)) || i > atomic.LoadInt64(&firstFailure) {
+					return
+				}
+				results[i], errs[i] = function.Call(ctx, items[i])
+				if errs[i] != nil {
+					recordFailure(&firstFailure, i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if i := firstFailure; i < int64(len(items)) {
+		return nil, errs[i]
+	}
+	return results, nil
 }
 
-func foldLeft(args ...types.SketchType) (types.SketchType, error) {
+// recordFailure lowers `firstFailure` to `i`, if `i` is lower.
+func recordFailure(firstFailure *int64, i int64) {
+	for {
+		current := atomic.LoadInt64(firstFailure)
+		if i >= current || atomic.CompareAndSwapInt64(firstFailure, current, i) {
+			return
+		}
+	}
+}
+
+// allocateSequenceLike records the cons cells allocated by creating a
+// sequence of `n` items with types.NewSequenceLike. Only lists are made of
+// cons cells, so nothing is recorded if `like` is a vector.
+func allocateSequenceLike(ctx context.Context, like types.SketchType, n int) error {
+	if _, ok := like.(*types.SketchVector); ok {
+		return nil
+	}
+	return limits.Allocate(ctx, n)
+}
+
+// consCells returns the number of cons cells in the lists `value` contains,
+// including `value` itself if it's a list.
+func consCells(value types.SketchType) int {
+	var items []types.SketchType
+	n := 0
+	switch value := value.(type) {
+	case *types.SketchList:
+		items = value.List.ToSlice()
+		n = len(items)
+	case *types.SketchVector:
+		items = value.Vector.ToSlice()
+	case *types.SketchHashMap:
+		items = append(value.Keys(), value.Values()...)
+	case *types.SketchSet:
+		items = value.Items()
+	}
+	for _, item := range items {
+		n += consCells(item)
+	}
+	return n
+}
+
+func foldLeft(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("fold-left", 3, args); err != nil {
 		return nil, err
 	}
@@ -112,14 +230,14 @@ func foldLeft(args ...types.SketchType) (types.SketchType, error) {
 		return nil, err
 	}
 
-	list, err := validation.ListArg("fold-left", args[2], 2)
+	items, err := validation.SequenceArg("fold-left", args[2], 2)
 	if err != nil {
 		return nil, err
 	}
 
 	collector := args[1]
-	for _, item := range list.List.ToSlice() {
-		result, err := function.Func(collector, item)
+	for _, item := range items {
+		result, err := function.Call(ctx, collector, item)
 		if err != nil {
 			return nil, err
 		}
@@ -129,13 +247,19 @@ func foldLeft(args ...types.SketchType) (types.SketchType, error) {
 	return collector, nil
 }
 
-func flatten(args ...types.SketchType) (types.SketchType, error) {
+func flatten(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("flatten", 1, args); err != nil {
+		return nil, err
+	}
 	list, err := validation.ListArg("flatten", args[0], 0)
 	if err != nil {
 		return nil, err
 	}
 
 	flattened := flattenRecur(list.List.ToSlice())
+	if err := allocateSequenceLike(ctx, list, len(flattened)); err != nil {
+		return nil, err
+	}
 
 	return &types.SketchList{
 		List: types.NewList(flattened),
@@ -158,7 +282,7 @@ func flattenRecur(items []types.SketchType) []types.SketchType {
 
 // (range 5) => (0 1 2 3 4)
 // (range 1 5) => (1 2 3 4)
-func sketchRange(args ...types.SketchType) (types.SketchType, error) {
+func sketchRange(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgsRange("range", 1, 2, args); err != nil {
 		return nil, err
 	}
@@ -186,6 +310,12 @@ func sketchRange(args ...types.SketchType) (types.SketchType, error) {
 		upper = rawUpper.Value
 	}
 
+	if upper > lower {
+		if err := limits.Allocate(ctx, upper-lower); err != nil {
+			return nil, err
+		}
+	}
+
 	var items []types.SketchType
 	for i := lower; i < upper; i++ {
 		items = append(items, &types.SketchInt{Value: i})
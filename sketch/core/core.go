@@ -2,60 +2,158 @@
 package core
 
 import (
+	"context"
+
 	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
 )
 
 var EnvironmentItems = map[string]types.SketchType{}
 
+// BuiltinCapabilities records the capabilities needed by each builtin in
+// EnvironmentItems which affects the world outside the interpreter. Builtins
+// which aren't listed don't need any.
+var BuiltinCapabilities = map[string]types.Capability{}
+
 func register(symbol string, f func(...types.SketchType) (types.SketchType, error)) {
+	EnvironmentItems[symbol] = &types.SketchFunction{
+		Func:      validation.RejectErrorArgs(symbol, f),
+		BoundName: symbol,
+	}
+}
+
+// registerRequiring registers a function which needs `capabilities` to run.
+// Sandboxed environments which don't grant them can't call it.
+func registerRequiring(symbol string, capabilities types.Capability, f func(...types.SketchType) (types.SketchType, error)) {
+	register(symbol, f)
+	BuiltinCapabilities[symbol] = capabilities
+}
+
+// registerAcceptingResults registers a function which can be passed error
+// results as arguments.
+func registerAcceptingResults(symbol string, f func(...types.SketchType) (types.SketchType, error)) {
 	EnvironmentItems[symbol] = &types.SketchFunction{
 		Func:      f,
 		BoundName: symbol,
 	}
 }
 
+// contextFunc is a builtin which is passed the context of the evaluation
+// calling it, because it calls other functions, or allocates lists, which
+// count towards the evaluation's limits.
+type contextFunc func(ctx context.Context, args ...types.SketchType) (types.SketchType, error)
+
+// registerContext registers a function which needs the context of the
+// evaluation calling it.
+func registerContext(symbol string, f contextFunc) {
+	registerContextAcceptingResults(symbol, func(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+		if err := validation.NoErrorArgs(symbol, args); err != nil {
+			return nil, err
+		}
+		return f(ctx, args...)
+	})
+}
+
+// registerContextAcceptingResults registers a function which needs the
+// context of the evaluation calling it, and can be passed error results as
+// arguments.
+func registerContextAcceptingResults(symbol string, f contextFunc) {
+	EnvironmentItems[symbol] = &types.SketchFunction{
+		FuncContext: f,
+		Func: func(args ...types.SketchType) (types.SketchType, error) {
+			return f(context.Background(), args...)
+		},
+		BoundName: symbol,
+	}
+}
+
 func init() {
-	register("prn", prn)
-	register("list", list)
+	registerAcceptingResults("pr-str", prStr)
+	registerContextAcceptingResults("list", list)
 	register("list?", isList)
+	registerAcceptingResults("vector", vector)
+	register("vector?", isVector)
+	registerAcceptingResults("assoc", assoc)
+	registerContextAcceptingResults("conj", conj)
+	register("keyword", keyword)
+	register("keyword?", isKeyword)
+	register("name", name)
+	register("set", set)
+	register("set?", isSet)
+	register("contains?", contains)
+	register("disj", disj)
+	register("union", union)
+	register("intersection", intersection)
+	register("difference", difference)
+	register("subset?", isSubset)
 	register("empty?", isEmpty)
 	register("count", count)
 	register("nth", nth)
-	register("read-string", readString)
-	register("slurp", slurp)
-	register("cons", cons)
-	register("concat", concat)
+	register("try-nth", tryNth)
+	registerContext("read-string", readString)
+	registerRequiring("slurp", types.ReadFiles, slurp)
+	registerContextAcceptingResults("cons", cons)
+	registerContext("concat", concat)
 	register("first", first)
 	register("rest", rest)
 	register("and", and)
 	register("or", or)
-	register("string-to-list", stringToList)
+	registerContext("string-to-list", stringToList)
 	register("length", length)
 
 	register("int", integer)
+	register("try-int", tryInteger)
 
 	register("+", add)
 	register("-", subtract)
 	register("*", multiply)
 	register("/", divide)
-	register("=", equals)
+	registerAcceptingResults("=", equals)
 	register("<", lt)
 	register("<=", lte)
 	register(">", gt)
 	register(">=", gte)
 	register("modulo", modulo)
 
-	register("apply", apply)
+	register("float", float)
+	register("floor", floor)
+	register("ceil", ceil)
+	register("round", round)
+	register("truncate", truncate)
+	register("numerator", numerator)
+	register("denominator", denominator)
+	register("rationalize", rationalize)
+
+	registerContext("apply", apply)
+
+	registerAcceptingResults("error", sketchError)
+	registerAcceptingResults("throw", throw)
+	register("exception?", isException)
+	register("exception-message", exceptionMessage)
+	register("exception-payload", exceptionPayload)
+	register("exception-stack", exceptionStack)
 
-	register("hashmap", hashMap)
-	register("hashmap-set", hashMapSet)
+	registerAcceptingResults("ok", ok)
+	registerAcceptingResults("err", sketchErr)
+	registerAcceptingResults("ok?", isOk)
+	registerAcceptingResults("unwrap", unwrap)
+	registerAcceptingResults("unwrap-or", unwrapOr)
+	registerContextAcceptingResults("and-then", andThen)
+
+	registerAcceptingResults("hashmap", hashMap)
+	registerAcceptingResults("ordered-hashmap", orderedHashMap)
+	registerAcceptingResults("hashmap-set", hashMapSet)
 	register("hashmap-get", hashMapGet)
-	register("hashmap-keys", hashMapKeys)
-	register("hashmap-values", hashMapValues)
-
-	register("map", sketchMap)
-	register("filter", filter)
-	register("fold-left", foldLeft)
-	register("flatten", flatten)
-	register("range", sketchRange)
+	register("hashmap-delete", hashMapDelete)
+	register("try-hashmap-get", tryHashMapGet)
+	registerContext("hashmap-keys", hashMapKeys)
+	registerContext("hashmap-values", hashMapValues)
+
+	registerContext("map", sketchMap)
+	registerContext("pmap", pmap)
+	registerContext("filter", filter)
+	registerContext("pfilter", pfilter)
+	registerContext("fold-left", foldLeft)
+	registerContext("flatten", flatten)
+	registerContext("range", sketchRange)
 }
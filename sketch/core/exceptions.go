@@ -0,0 +1,135 @@
+package core
+
+import (
+	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
+)
+
+// sketchError raises an error with the given message. An optional payload can
+// be supplied, which is made available to any `catch` form which catches the
+// error.
+// > (error "not found")
+// > (error "not found" {"key" 1})
+func sketchError(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgsRange("error", 1, 2, args); err != nil {
+		return nil, err
+	}
+	message, err := validation.StringArg("error", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload types.SketchType = &types.SketchNil{}
+	if len(args) == 2 {
+		payload = args[1]
+	}
+
+	return nil, &errors.UserError{
+		Message: message.Value,
+		Payload: payload,
+	}
+}
+
+// throw raises its argument as an error. If the argument is an exception
+// caught by a `catch` form, the original error is rethrown. Otherwise, the
+// argument is used as the payload of a new error.
+func throw(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("throw", 1, args); err != nil {
+		return nil, err
+	}
+
+	if exception, ok := args[0].(*types.SketchException); ok {
+		return nil, exception.Err
+	}
+
+	return nil, &errors.UserError{
+		Message: args[0].String(),
+		Payload: args[0],
+	}
+}
+
+func isException(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("exception?", 1, args); err != nil {
+		return nil, err
+	}
+	_, ok := args[0].(*types.SketchException)
+	return &types.SketchBoolean{
+		Value: ok,
+	}, nil
+}
+
+func exceptionMessage(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("exception-message", 1, args); err != nil {
+		return nil, err
+	}
+	exception, err := validation.ExceptionArg("exception-message", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	return &types.SketchString{
+		Value: exception.Message,
+	}, nil
+}
+
+func exceptionPayload(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("exception-payload", 1, args); err != nil {
+		return nil, err
+	}
+	exception, err := validation.ExceptionArg("exception-payload", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	return exception.Payload, nil
+}
+
+// exceptionStack returns the call stack which led to the exception, as a list
+// of hash maps, outermost call first. Each describes a function call, with
+// the keys :function, :args, :file, :line, :column and :tail-calls. :args
+// holds at most the first 8 arguments, and :tail-calls is the number of
+// frames tail call optimisation elided before this one.
+// > (try (f 1) (catch e (map (fn (frame) (:function frame)) (exception-stack e))))
+// ("f" "error")
+func exceptionStack(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("exception-stack", 1, args); err != nil {
+		return nil, err
+	}
+	exception, err := validation.ExceptionArg("exception-stack", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var frames []types.SketchType
+	for _, frame := range exception.Stack.Frames() {
+		hashMap, err := stackFrameHashMap(frame)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, hashMap)
+	}
+	return &types.SketchList{
+		List: types.NewList(frames),
+	}, nil
+}
+
+// stackFrameHashMap converts `frame` to the hash map exception-stack returns.
+// The position keys are nil if the position isn't known.
+func stackFrameHashMap(frame *types.StackFrame) (*types.SketchHashMap, error) {
+	var file, line, column types.SketchType = &types.SketchNil{}, &types.SketchNil{}, &types.SketchNil{}
+	if frame.Pos.IsValid() {
+		line = &types.SketchInt{Value: frame.Pos.Line}
+		column = &types.SketchInt{Value: frame.Pos.Column}
+		if frame.Pos.File != "" {
+			file = &types.SketchString{Value: frame.Pos.File}
+		}
+	}
+
+	return types.NewOrderedSketchHashMap([]types.SketchType{
+		types.NewKeyword("function"), &types.SketchString{Value: frame.FunctionName},
+		types.NewKeyword("args"), &types.SketchList{List: types.NewList(frame.Args)},
+		types.NewKeyword("file"), file,
+		types.NewKeyword("line"), line,
+		types.NewKeyword("column"), column,
+		types.NewKeyword("tail-calls"), &types.SketchInt{Value: frame.TailCalls},
+	})
+}
@@ -1,28 +1,36 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"reflect"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/jamesroutley/sketch/sketch/limits"
 	"github.com/jamesroutley/sketch/sketch/printer"
 	"github.com/jamesroutley/sketch/sketch/reader"
 	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/jamesroutley/sketch/sketch/validation"
 )
 
-func prn(args ...types.SketchType) (types.SketchType, error) {
+// prStr returns its arguments printed as they'd be printed by prn, separated
+// by spaces. The result can be read back with read-string.
+func prStr(args ...types.SketchType) (types.SketchType, error) {
 	ss := make([]string, len(args))
 	for i, arg := range args {
 		ss[i] = printer.PrStr(arg)
 	}
-	fmt.Println(strings.Join(ss, " "))
-	return &types.SketchNil{}, nil
+	return &types.SketchString{
+		Value: strings.Join(ss, " "),
+	}, nil
 }
 
-func list(args ...types.SketchType) (types.SketchType, error) {
+func list(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	if err := limits.Allocate(ctx, len(args)); err != nil {
+		return nil, err
+	}
 	return &types.SketchList{
 		List: types.NewList(args),
 	}, nil
@@ -36,14 +44,21 @@ func isList(args ...types.SketchType) (types.SketchType, error) {
 }
 
 func isEmpty(args ...types.SketchType) (types.SketchType, error) {
-	list, ok := args[0].(*types.SketchList)
-	if !ok {
-		return nil, fmt.Errorf("first argument to empty? isn't a list")
+	switch arg := args[0].(type) {
+	case *types.SketchList:
+		return &types.SketchBoolean{
+			Value: arg.List.Empty(),
+		}, nil
+	case *types.SketchVector:
+		return &types.SketchBoolean{
+			Value: arg.Vector.Empty(),
+		}, nil
+	case *types.SketchSet:
+		return &types.SketchBoolean{
+			Value: arg.Len() == 0,
+		}, nil
 	}
-
-	return &types.SketchBoolean{
-		Value: list.List.Empty(),
-	}, nil
+	return nil, fmt.Errorf("first argument to empty? isn't a list")
 }
 
 func count(args ...types.SketchType) (types.SketchType, error) {
@@ -52,6 +67,16 @@ func count(args ...types.SketchType) (types.SketchType, error) {
 			Value: 0,
 		}, nil
 	}
+	switch arg := args[0].(type) {
+	case *types.SketchVector:
+		return &types.SketchInt{
+			Value: arg.Vector.Len(),
+		}, nil
+	case *types.SketchSet:
+		return &types.SketchInt{
+			Value: arg.Len(),
+		}, nil
+	}
 	list, err := validation.ListArg("count", args[0], 0)
 	if err != nil {
 		return nil, err
@@ -63,6 +88,20 @@ func count(args ...types.SketchType) (types.SketchType, error) {
 }
 
 func nth(args ...types.SketchType) (types.SketchType, error) {
+	if vector, ok := args[0].(*types.SketchVector); ok {
+		n, err := validation.IntArg("nth", args[1], 1)
+		if err != nil {
+			return nil, err
+		}
+		item, ok := vector.Vector.Nth(n.Value)
+		if !ok {
+			return nil, fmt.Errorf(
+				"nth: index out of range - %d, with length %d", n.Value, vector.Vector.Len(),
+			)
+		}
+		return item, nil
+	}
+
 	list, err := validation.ListArg("nth", args[0], 0)
 	if err != nil {
 		return nil, err
@@ -83,39 +122,104 @@ func nth(args ...types.SketchType) (types.SketchType, error) {
 	return items[n.Value], nil
 }
 
-func equals(args ...types.SketchType) (types.SketchType, error) {
-	if err := validation.NArgs("=", 2, args); err != nil {
+// tryNth is a variant of nth which returns an error result, rather than raising
+// an error, if the index is out of range.
+func tryNth(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("try-nth", 2, args); err != nil {
+		return nil, err
+	}
+	items, err := validation.SequenceArg("try-nth", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := validation.IntArg("try-nth", args[1], 1)
+	if err != nil {
 		return nil, err
 	}
 
-	return &types.SketchBoolean{
-		Value: equalsInternal(args[0], args[1]),
+	if n.Value < 0 || n.Value >= len(items) {
+		return errResult(fmt.Errorf(
+			"try-nth: index out of range - %d, with length %d", n.Value, len(items),
+		)), nil
+	}
+
+	return &types.SketchResult{
+		Ok:    true,
+		Value: items[n.Value],
 	}, nil
 }
 
+// equals returns whether all of its arguments are equal
+// > (= 1 1.0 1)
+// true
+func equals(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.MinArgs("=", 1, args); err != nil {
+		return nil, err
+	}
+
+	for i := 1; i < len(args); i++ {
+		if !equalsInternal(args[i-1], args[i]) {
+			return &types.SketchBoolean{Value: false}, nil
+		}
+	}
+	return &types.SketchBoolean{Value: true}, nil
+}
+
+// equalsInternal returns whether `aa` and `bb` are equal. Values are compared
+// structurally where their type has a notion of equal contents. Other values,
+// such as functions, are only equal to themselves.
 func equalsInternal(aa types.SketchType, bb types.SketchType) bool {
-	if reflect.TypeOf(aa) != reflect.TypeOf(bb) {
-		return false
+	// Numbers are compared by value, regardless of their type
+	if types.IsNumber(aa) && types.IsNumber(bb) {
+		return numbersEqual(aa, bb)
 	}
 
-	switch a := aa.(type) {
-	case *types.SketchList:
-		b := bb.(*types.SketchList)
-		aSlice := a.List.ToSlice()
-		bSlice := b.List.ToSlice()
-		if len(aSlice) != len(bSlice) {
+	// Lists and vectors are equal if they contain equal items, in the same
+	// order
+	if aSlice, ok := sequentialItems(aa); ok {
+		bSlice, ok := sequentialItems(bb)
+		if !ok || len(aSlice) != len(bSlice) {
 			return false
 		}
-
 		for i := range aSlice {
 			if !equalsInternal(aSlice[i], bSlice[i]) {
 				return false
 			}
 		}
+		return true
+	}
 
-	case *types.SketchInt:
-		b := bb.(*types.SketchInt)
-		return a.Value == b.Value
+	if reflect.TypeOf(aa) != reflect.TypeOf(bb) {
+		return false
+	}
+
+	switch a := aa.(type) {
+
+	case *types.SketchSet:
+		// Sets are equal if they contain the same items
+		b := bb.(*types.SketchSet)
+		return a.Len() == b.Len() && subset(a, b)
+
+	case *types.SketchHashMap:
+		// Hash maps are equal if they have the same keys, and each key maps
+		// to an equal value. The order of ordered hash maps isn't compared
+		b := bb.(*types.SketchHashMap)
+		if a.Len() != b.Len() {
+			return false
+		}
+		for _, key := range a.Keys() {
+			bValue, err := b.Get(key)
+			if err != nil {
+				// b doesn't contain the key
+				return false
+			}
+			// a contains all of its keys, so Get can't error
+			aValue, _ := a.Get(key)
+			if !equalsInternal(aValue, bValue) {
+				return false
+			}
+		}
+		return true
 
 	case *types.SketchBoolean:
 		b := bb.(*types.SketchBoolean)
@@ -125,6 +229,10 @@ func equalsInternal(aa types.SketchType, bb types.SketchType) bool {
 		b := bb.(*types.SketchSymbol)
 		return a.Value == b.Value
 
+	case *types.SketchKeyword:
+		b := bb.(*types.SketchKeyword)
+		return a.Equals(b)
+
 	case *types.SketchString:
 		b := bb.(*types.SketchString)
 		return a.Value == b.Value
@@ -133,20 +241,45 @@ func equalsInternal(aa types.SketchType, bb types.SketchType) bool {
 		// Nils don't have values, so they're always equal
 		return true
 
-	default:
-		log.Fatalf("equals unimplemented for type %T", a)
+	case *types.SketchResult:
+		b := bb.(*types.SketchResult)
+		return a.Ok == b.Ok && equalsInternal(a.Value, b.Value)
 	}
 
-	return true
+	// Functions, modules, exceptions and other values without comparable
+	// contents are equal if they're the same value
+	return aa == bb
+}
+
+// sequentialItems returns the items in `t`, if it's a list or vector.
+func sequentialItems(t types.SketchType) ([]types.SketchType, bool) {
+	switch t := t.(type) {
+	case *types.SketchList:
+		return t.List.ToSlice(), true
+	case *types.SketchVector:
+		return t.Vector.ToSlice(), true
+	}
+	return nil, false
 }
 
-func readString(args ...types.SketchType) (types.SketchType, error) {
+func readString(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("read-string", 1, args); err != nil {
+		return nil, err
+	}
 	arg, ok := args[0].(*types.SketchString)
 	if !ok {
 		return nil, fmt.Errorf("read-string takes a string")
 	}
 
-	return reader.Read(arg.Value)
+	form, err := reader.Read(arg.Value)
+	if err != nil {
+		return nil, err
+	}
+	// Account for the lists the form is made of
+	if err := limits.Allocate(ctx, consCells(form)); err != nil {
+		return nil, err
+	}
+	return form, nil
 }
 
 func slurp(args ...types.SketchType) (types.SketchType, error) {
@@ -168,33 +301,49 @@ func slurp(args ...types.SketchType) (types.SketchType, error) {
 // cons prepends arg1 onto the list at arg2
 // >(cons 1 (quote (2 3)))
 // (1 2 3)
-func cons(args ...types.SketchType) (types.SketchType, error) {
+func cons(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	list, ok := args[1].(*types.SketchList)
 	if !ok {
 		return nil, fmt.Errorf("cons takes a list as its second argument")
 	}
+	if err := limits.Allocate(ctx, 1); err != nil {
+		return nil, err
+	}
 	return &types.SketchList{
 		List: list.List.Conj(args[0]),
 	}, nil
 }
 
-// concat takes a number of lists and concatenates them together
+// concat takes a number of lists or vectors and concatenates them together.
+// The result has the same type as the first argument
 // > (concat (list 1 2) (list 3 4))
 // (1 2 3 4)
-func concat(args ...types.SketchType) (types.SketchType, error) {
+// > (concat [1 2] (list 3 4))
+// [1 2 3 4]
+func concat(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	var allItems []types.SketchType
 
 	for _, arg := range args {
-		list, ok := arg.(*types.SketchList)
+		items, ok := types.SequenceItems(arg)
 		if !ok {
-			return nil, fmt.Errorf("concat takes lists as arguments")
+			return nil, fmt.Errorf("concat takes lists or vectors as arguments")
 		}
-		allItems = append(allItems, list.List.ToSlice()...)
+		allItems = append(allItems, items...)
+	}
+	var like types.SketchType
+	if len(args) > 0 {
+		like = args[0]
+	}
+	if err := allocateSequenceLike(ctx, like, len(allItems)); err != nil {
+		return nil, err
 	}
 
-	return &types.SketchList{
-		List: types.NewList(allItems),
-	}, nil
+	if len(args) == 0 {
+		return &types.SketchList{
+			List: types.NewList(allItems),
+		}, nil
+	}
+	return types.NewSequenceLike(args[0], allItems), nil
 }
 
 func first(args ...types.SketchType) (types.SketchType, error) {
@@ -203,6 +352,12 @@ func first(args ...types.SketchType) (types.SketchType, error) {
 		return arg, nil
 	case *types.SketchList:
 		return arg.List.First(), nil
+	case *types.SketchVector:
+		item, ok := arg.Vector.Nth(0)
+		if !ok {
+			return &types.SketchNil{}, nil
+		}
+		return item, nil
 	case *types.SketchString:
 		runes := []rune(arg.Value)
 		if len(runes) == 0 {
@@ -224,6 +379,10 @@ func rest(args ...types.SketchType) (types.SketchType, error) {
 		return &types.SketchList{
 			List: arg.List.Rest(),
 		}, nil
+	case *types.SketchVector:
+		return &types.SketchVector{
+			Vector: arg.Vector.Rest(),
+		}, nil
 	case *types.SketchString:
 		runes := []rune(arg.Value)
 		if len(runes) <= 1 {
@@ -269,7 +428,7 @@ func or(args ...types.SketchType) (types.SketchType, error) {
 	}, nil
 }
 
-func stringToList(args ...types.SketchType) (types.SketchType, error) {
+func stringToList(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("string-to-list", 1, args); err != nil {
 		return nil, err
 	}
@@ -278,6 +437,9 @@ func stringToList(args ...types.SketchType) (types.SketchType, error) {
 		return nil, err
 	}
 
+	if err := limits.Allocate(ctx, utf8.RuneCountInString(str.Value)); err != nil {
+		return nil, err
+	}
 	var chars []types.SketchType
 	for _, r := range str.Value {
 		chars = append(chars, &types.SketchString{
@@ -302,6 +464,12 @@ func length(args ...types.SketchType) (types.SketchType, error) {
 	case *types.SketchString:
 		runes := []rune(arg.Value)
 		itemLength = len(runes)
+	case *types.SketchVector:
+		itemLength = arg.Vector.Len()
+	case *types.SketchSet:
+		itemLength = arg.Len()
+	case *types.SketchHashMap:
+		itemLength = arg.Len()
 	default:
 		return nil, fmt.Errorf("length called with type %s, only supports list and string", arg.Type())
 	}
@@ -313,7 +481,7 @@ func length(args ...types.SketchType) (types.SketchType, error) {
 
 // apply
 // (apply + (list 1 2 3)) is equivalent to (+ 1 2 3)
-func apply(args ...types.SketchType) (types.SketchType, error) {
+func apply(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("apply", 2, args); err != nil {
 		return nil, err
 	}
@@ -321,12 +489,12 @@ func apply(args ...types.SketchType) (types.SketchType, error) {
 	if err != nil {
 		return nil, err
 	}
-	list, err := validation.ListArg("apply", args[1], 1)
+	items, err := validation.SequenceArg("apply", args[1], 1)
 	if err != nil {
 		return nil, err
 	}
 
-	return function.Func(list.List.ToSlice()...)
+	return function.Call(ctx, items...)
 }
 
 // func list(args ...types.SketchType) (types.SketchType, error) {
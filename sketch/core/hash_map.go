@@ -1,8 +1,10 @@
 package core
 
 import (
+	"context"
 	"strings"
 
+	"github.com/jamesroutley/sketch/sketch/limits"
 	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/jamesroutley/sketch/sketch/validation"
 )
@@ -11,6 +13,14 @@ func hashMap(args ...types.SketchType) (types.SketchType, error) {
 	return types.NewSketchHashMap(args)
 }
 
+// orderedHashMap creates a hash map which keeps its keys in the order they
+// were first added, rather than sorting them
+// > (ordered-hashmap "b" 1 "a" 2)
+// {"b" 1 "a" 2}
+func orderedHashMap(args ...types.SketchType) (types.SketchType, error) {
+	return types.NewOrderedSketchHashMap(args)
+}
+
 func hashMapSet(args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("hashmap-set", 3, args); err != nil {
 		return nil, err
@@ -29,6 +39,22 @@ func hashMapSet(args ...types.SketchType) (types.SketchType, error) {
 	return newHashMap, nil
 }
 
+// hashMapDelete returns a new hashmap, without the specified key
+// > (hashmap-delete {"a" 1 "b" 2} "a")
+// {"b" 2}
+func hashMapDelete(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("hashmap-delete", 2, args); err != nil {
+		return nil, err
+	}
+
+	hashmap, err := validation.HashMapArg("hashmap-delete", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashmap.Delete(args[1])
+}
+
 func hashMapGet(args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgsRange("hashmap-get", 2, 3, args); err != nil {
 		return nil, err
@@ -52,7 +78,36 @@ func hashMapGet(args ...types.SketchType) (types.SketchType, error) {
 	return value, nil
 }
 
-func hashMapKeys(args ...types.SketchType) (types.SketchType, error) {
+// tryHashMapGet is a variant of hashmap-get which returns an error result,
+// rather than raising an error, if the map doesn't contain the key.
+func tryHashMapGet(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("try-hashmap-get", 2, args); err != nil {
+		return nil, err
+	}
+
+	hashmap, err := validation.HashMapArg("try-hashmap-get", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := types.ValidHashMapKey(args[1]); err != nil {
+		return nil, err
+	}
+
+	// We've validated the key, so the only error Get can return is that the
+	// key isn't in the map
+	value, err := hashmap.Get(args[1])
+	if err != nil {
+		return errResult(err), nil
+	}
+
+	return &types.SketchResult{
+		Ok:    true,
+		Value: value,
+	}, nil
+}
+
+func hashMapKeys(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("hashmap-keys", 1, args); err != nil {
 		return nil, err
 	}
@@ -61,13 +116,16 @@ func hashMapKeys(args ...types.SketchType) (types.SketchType, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := limits.Allocate(ctx, hashmap.Len()); err != nil {
+		return nil, err
+	}
 
 	return &types.SketchList{
 		List: types.NewList(hashmap.Keys()),
 	}, nil
 }
 
-func hashMapValues(args ...types.SketchType) (types.SketchType, error) {
+func hashMapValues(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("hashmap-values", 1, args); err != nil {
 		return nil, err
 	}
@@ -76,6 +134,9 @@ func hashMapValues(args ...types.SketchType) (types.SketchType, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := limits.Allocate(ctx, hashmap.Len()); err != nil {
+		return nil, err
+	}
 
 	return &types.SketchList{
 		List: types.NewList(hashmap.Values()),
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jamesroutley/sketch/sketch/printer"
+	"github.com/jamesroutley/sketch/sketch/types"
+)
+
+// IO is the set of streams Sketch's IO functions, such as prn, read from and
+// write to. Programs which embed Sketch can use it to capture output.
+type IO struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  io.Reader
+}
+
+// StandardIO returns an IO which uses the process's standard streams.
+func StandardIO() *IO {
+	return &IO{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Stdin:  os.Stdin,
+	}
+}
+
+// IONames lists the names of the builtins returned by IOFunctions.
+var IONames = []string{"prn", "eprn", "read-line"}
+
+// IOFunctions returns the builtins which do IO, using the streams in
+// `streams`. Unlike the rest of the builtins, which are in EnvironmentItems,
+// they're created for each root environment, so each environment can use
+// different streams.
+func IOFunctions(streams *IO) map[string]types.SketchType {
+	stdin := bufio.NewReader(streams.Stdin)
+	functions := map[string]func(...types.SketchType) (types.SketchType, error){
+		"prn":  printTo(streams.Stdout),
+		"eprn": printTo(streams.Stderr),
+		"read-line": func(args ...types.SketchType) (types.SketchType, error) {
+			return readLine(stdin, args...)
+		},
+	}
+
+	items := make(map[string]types.SketchType, len(functions))
+	for name, f := range functions {
+		items[name] = &types.SketchFunction{
+			Func:      f,
+			BoundName: name,
+		}
+	}
+	return items
+}
+
+// printTo returns a function which prints its arguments to `w`, separated by
+// spaces.
+func printTo(w io.Writer) func(...types.SketchType) (types.SketchType, error) {
+	return func(args ...types.SketchType) (types.SketchType, error) {
+		ss := make([]string, len(args))
+		for i, arg := range args {
+			ss[i] = printer.PrStr(arg)
+		}
+		fmt.Fprintln(w, strings.Join(ss, " "))
+		return &types.SketchNil{}, nil
+	}
+}
+
+// readLine reads a line from `r`, without its trailing newline. Returns nil
+// once there's nothing left to read.
+func readLine(r *bufio.Reader, args ...types.SketchType) (types.SketchType, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("read-line takes no arguments, got %d", len(args))
+	}
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return &types.SketchNil{}, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &types.SketchString{
+		Value: strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"),
+	}, nil
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
+)
+
+// keyword converts a string or symbol to a keyword
+// > (keyword "name")
+// :name
+func keyword(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("keyword", 1, args); err != nil {
+		return nil, err
+	}
+	switch arg := args[0].(type) {
+	case *types.SketchKeyword:
+		return arg, nil
+	case *types.SketchString:
+		return types.NewKeyword(arg.Value), nil
+	case *types.SketchSymbol:
+		return types.NewKeyword(arg.Value), nil
+	default:
+		return nil, fmt.Errorf("keyword: unable to convert type %s to a keyword", arg.Type())
+	}
+}
+
+func isKeyword(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("keyword?", 1, args); err != nil {
+		return nil, err
+	}
+	_, ok := args[0].(*types.SketchKeyword)
+	return &types.SketchBoolean{
+		Value: ok,
+	}, nil
+}
+
+// name returns the name of a keyword or symbol, as a string
+// > (name :name)
+// "name"
+func name(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("name", 1, args); err != nil {
+		return nil, err
+	}
+	switch arg := args[0].(type) {
+	case *types.SketchKeyword:
+		return &types.SketchString{Value: arg.Name()}, nil
+	case *types.SketchSymbol:
+		return &types.SketchString{Value: arg.Value}, nil
+	case *types.SketchString:
+		return arg, nil
+	default:
+		return nil, fmt.Errorf("name: unable to get the name of type %s", arg.Type())
+	}
+}
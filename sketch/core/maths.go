@@ -1,119 +1,548 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strconv"
 
 	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/jamesroutley/sketch/sketch/validation"
 )
 
-func add(args ...types.SketchType) (types.SketchType, error) {
-	switch a := args[0].(type) {
-	case *types.SketchInt:
-		sum := a.Value
-		for _, arg := range args[1:] {
-			b, ok := arg.(*types.SketchInt)
-			if !ok {
-				return nil, fmt.Errorf("addition between different types")
+// Sketch's numeric types form a tower, ordered from least to most general.
+// When a maths function is passed numbers of different types, the less
+// general number is converted to the type of the more general one, before the
+// operation is applied. e.g. (+ 1 1.5) converts 1 to 1.0, then adds two
+// floats.
+type numericLevel int
+
+const (
+	intLevel numericLevel = iota
+	bigIntLevel
+	ratioLevel
+	floatLevel
+)
+
+func levelOf(n types.SketchType) numericLevel {
+	switch n.(type) {
+	case *types.SketchBigInt:
+		return bigIntLevel
+	case *types.SketchRatio:
+		return ratioLevel
+	case *types.SketchFloat:
+		return floatLevel
+	}
+	return intLevel
+}
+
+// numericOp implements a binary operation for each level of the numeric
+// tower. Callers must validate that the operands are numbers.
+//
+// Operations on ints which overflow should fall back to calling onBigInts.
+// Operations on big ints and ratios should return their result with
+// types.NewBigInt and types.NewRatio, so results which fit in a less general
+// type are demoted back to one.
+type numericOp struct {
+	onInts    func(a, b int) (types.SketchType, error)
+	onBigInts func(a, b *big.Int) (types.SketchType, error)
+	onRatios  func(a, b *big.Rat) (types.SketchType, error)
+	onFloats  func(a, b float64) (types.SketchType, error)
+}
+
+// errDivisionByZero is returned when dividing an exact number by zero.
+// Dividing a float by zero returns an infinite or NaN float instead.
+var errDivisionByZero = errors.New("division by zero")
+
+func (op *numericOp) apply(a, b types.SketchType) (types.SketchType, error) {
+	level := levelOf(a)
+	if levelOf(b) > level {
+		level = levelOf(b)
+	}
+
+	switch level {
+	case floatLevel:
+		return op.onFloats(types.ToFloat64(a), types.ToFloat64(b))
+	case ratioLevel:
+		return op.onRatios(types.ToRat(a), types.ToRat(b))
+	case bigIntLevel:
+		return op.onBigInts(types.ToBigInt(a), types.ToBigInt(b))
+	default:
+		return op.onInts(a.(*types.SketchInt).Value, b.(*types.SketchInt).Value)
+	}
+}
+
+func addBigInts(a, b *big.Int) (types.SketchType, error) {
+	return types.NewBigInt(new(big.Int).Add(a, b)), nil
+}
+
+var addOp = &numericOp{
+	onInts: func(a, b int) (types.SketchType, error) {
+		sum := a + b
+		// Overflow happened if the sum moved in the wrong direction
+		if (sum > a) != (b > 0) {
+			return addBigInts(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		return &types.SketchInt{Value: sum}, nil
+	},
+	onBigInts: addBigInts,
+	onRatios: func(a, b *big.Rat) (types.SketchType, error) {
+		return types.NewRatio(new(big.Rat).Add(a, b)), nil
+	},
+	onFloats: func(a, b float64) (types.SketchType, error) {
+		return &types.SketchFloat{Value: a + b}, nil
+	},
+}
+
+func subtractBigInts(a, b *big.Int) (types.SketchType, error) {
+	return types.NewBigInt(new(big.Int).Sub(a, b)), nil
+}
+
+var subtractOp = &numericOp{
+	onInts: func(a, b int) (types.SketchType, error) {
+		difference := a - b
+		// Overflow happened if the difference moved in the wrong direction
+		if (difference < a) != (b > 0) {
+			return subtractBigInts(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		return &types.SketchInt{Value: difference}, nil
+	},
+	onBigInts: subtractBigInts,
+	onRatios: func(a, b *big.Rat) (types.SketchType, error) {
+		return types.NewRatio(new(big.Rat).Sub(a, b)), nil
+	},
+	onFloats: func(a, b float64) (types.SketchType, error) {
+		return &types.SketchFloat{Value: a - b}, nil
+	},
+}
+
+func multiplyBigInts(a, b *big.Int) (types.SketchType, error) {
+	return types.NewBigInt(new(big.Int).Mul(a, b)), nil
+}
+
+var multiplyOp = &numericOp{
+	onInts: func(a, b int) (types.SketchType, error) {
+		product := a * b
+		// Overflow happened if we can't get back to the operands by
+		// dividing. The one case division doesn't catch is
+		// math.MinInt * -1, which overflows back to math.MinInt.
+		if a != 0 && (product/a != b || (a == -1 && b == math.MinInt)) {
+			return multiplyBigInts(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		return &types.SketchInt{Value: product}, nil
+	},
+	onBigInts: multiplyBigInts,
+	onRatios: func(a, b *big.Rat) (types.SketchType, error) {
+		return types.NewRatio(new(big.Rat).Mul(a, b)), nil
+	},
+	onFloats: func(a, b float64) (types.SketchType, error) {
+		return &types.SketchFloat{Value: a * b}, nil
+	},
+}
+
+// divideBigInts is also used when only one of the operands is big, in which
+// case the other, which may be 0, is promoted to a big int.
+func divideBigInts(a, b *big.Int) (types.SketchType, error) {
+	if b.Sign() == 0 {
+		return nil, errDivisionByZero
+	}
+	return types.NewRatio(new(big.Rat).SetFrac(a, b)), nil
+}
+
+// Dividing two ints returns an int if the division is exact, and a ratio if
+// it isn't.
+var divideOp = &numericOp{
+	onInts: func(a, b int) (types.SketchType, error) {
+		if b == 0 {
+			return nil, errDivisionByZero
+		}
+		// math.MinInt / -1 overflows
+		if a == math.MinInt && b == -1 {
+			return divideBigInts(big.NewInt(int64(a)), big.NewInt(int64(b)))
+		}
+		if a%b == 0 {
+			return &types.SketchInt{Value: a / b}, nil
+		}
+		return types.NewRatio(big.NewRat(int64(a), int64(b))), nil
+	},
+	onBigInts: divideBigInts,
+	onRatios: func(a, b *big.Rat) (types.SketchType, error) {
+		if b.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		return types.NewRatio(new(big.Rat).Quo(a, b)), nil
+	},
+	onFloats: func(a, b float64) (types.SketchType, error) {
+		return &types.SketchFloat{Value: a / b}, nil
+	},
+}
+
+var moduloOp = &numericOp{
+	onInts: func(a, b int) (types.SketchType, error) {
+		if b == 0 {
+			return nil, errDivisionByZero
+		}
+		// math.MinInt % -1 is 0, so unlike division this can't overflow
+		return &types.SketchInt{Value: a % b}, nil
+	},
+	onBigInts: func(a, b *big.Int) (types.SketchType, error) {
+		if b.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		// Rem truncates, like Go's % operator
+		return types.NewBigInt(new(big.Int).Rem(a, b)), nil
+	},
+	onRatios: func(a, b *big.Rat) (types.SketchType, error) {
+		if b.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		// a - b * truncate(a / b), which matches Rem's behaviour for ints
+		quotient := new(big.Rat).SetInt(truncateRat(new(big.Rat).Quo(a, b)))
+		return types.NewRatio(new(big.Rat).Sub(a, quotient.Mul(quotient, b))), nil
+	},
+	onFloats: func(a, b float64) (types.SketchType, error) {
+		return &types.SketchFloat{Value: math.Mod(a, b)}, nil
+	},
+}
+
+// compareOp returns a numericOp which compares two numbers. `compare` is
+// passed -1, 0 or 1, depending on whether a < b, a == b or a > b.
+func compareOp(compare func(cmp int) bool) *numericOp {
+	return &numericOp{
+		onInts: func(a, b int) (types.SketchType, error) {
+			cmp := 0
+			if a < b {
+				cmp = -1
+			} else if a > b {
+				cmp = 1
 			}
-			sum += b.Value
+			return &types.SketchBoolean{Value: compare(cmp)}, nil
+		},
+		onBigInts: func(a, b *big.Int) (types.SketchType, error) {
+			return &types.SketchBoolean{Value: compare(a.Cmp(b))}, nil
+		},
+		onRatios: func(a, b *big.Rat) (types.SketchType, error) {
+			return &types.SketchBoolean{Value: compare(a.Cmp(b))}, nil
+		},
+		onFloats: func(a, b float64) (types.SketchType, error) {
+			// Comparisons involving NaN are always false
+			if math.IsNaN(a) || math.IsNaN(b) {
+				return &types.SketchBoolean{Value: false}, nil
+			}
+			cmp := 0
+			if a < b {
+				cmp = -1
+			} else if a > b {
+				cmp = 1
+			}
+			return &types.SketchBoolean{Value: compare(cmp)}, nil
+		},
+	}
+}
+
+var (
+	ltOp  = compareOp(func(cmp int) bool { return cmp < 0 })
+	lteOp = compareOp(func(cmp int) bool { return cmp <= 0 })
+	gtOp  = compareOp(func(cmp int) bool { return cmp > 0 })
+	gteOp = compareOp(func(cmp int) bool { return cmp >= 0 })
+	eqOp  = compareOp(func(cmp int) bool { return cmp == 0 })
+)
+
+// foldNumbers applies `op` to `numbers` from left to right, so
+// (- 10 2 3) is evaluated as (10 - 2) - 3.
+func foldNumbers(
+	fnName string, op *numericOp, numbers []types.SketchType,
+) (types.SketchType, error) {
+	result := numbers[0]
+	for _, number := range numbers[1:] {
+		var err error
+		result, err = op.apply(result, number)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fnName, err)
 		}
-		return &types.SketchInt{
-			Value: sum,
-		}, nil
-	case *types.SketchString:
-		sum := a.Value
-		for _, arg := range args[1:] {
-			b, ok := arg.(*types.SketchString)
-			if !ok {
-				return nil, fmt.Errorf("addition between different types")
+	}
+	return result, nil
+}
+
+// compareNumbers returns whether the comparison `op` holds between each
+// adjacent pair of arguments, so (< 1 2 3) checks that 1 < 2 and 2 < 3.
+func compareNumbers(
+	fnName string, op *numericOp, args []types.SketchType,
+) (types.SketchType, error) {
+	numbers, err := validation.MinNumberArgs(fnName, 1, args)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(numbers); i++ {
+		result, err := op.apply(numbers[i-1], numbers[i])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fnName, err)
+		}
+		if !result.(*types.SketchBoolean).Value {
+			return result, nil
+		}
+	}
+	return &types.SketchBoolean{Value: true}, nil
+}
+
+// add adds numbers, or concatenates strings
+// > (+ 1 2 3)
+// 6
+// > (+)
+// 0
+func add(args ...types.SketchType) (types.SketchType, error) {
+	if len(args) > 0 {
+		switch a := args[0].(type) {
+		case *types.SketchString:
+			sum := a.Value
+			for _, arg := range args[1:] {
+				b, ok := arg.(*types.SketchString)
+				if !ok {
+					return nil, fmt.Errorf("addition between different types")
+				}
+				sum += b.Value
 			}
-			sum += b.Value
+			return &types.SketchString{
+				Value: sum,
+			}, nil
 		}
-		return &types.SketchString{
-			Value: sum,
-		}, nil
 	}
-	return nil, fmt.Errorf("unsupported first arg to +")
+
+	numbers, err := validation.MinNumberArgs("+", 0, args)
+	if err != nil {
+		return nil, err
+	}
+	return foldNumbers("+", addOp, append([]types.SketchType{&types.SketchInt{Value: 0}}, numbers...))
 }
 
+// subtract subtracts each subsequent number from the first. With one
+// argument, it negates it.
+// > (- 10 2 3)
+// 5
+// > (- 1)
+// -1
 func subtract(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs("-", 2, args)
+	numbers, err := validation.MinNumberArgs("-", 1, args)
 	if err != nil {
 		return nil, err
 	}
-	return &types.SketchInt{
-		Value: numbers[0].Value - numbers[1].Value,
-	}, nil
+	if len(numbers) == 1 {
+		numbers = []types.SketchType{&types.SketchInt{Value: 0}, numbers[0]}
+	}
+	return foldNumbers("-", subtractOp, numbers)
 }
 
+// multiply multiplies numbers
+// > (* 1 2 3)
+// 6
+// > (*)
+// 1
 func multiply(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs("*", 2, args)
+	numbers, err := validation.MinNumberArgs("*", 0, args)
 	if err != nil {
 		return nil, err
 	}
-	return &types.SketchInt{
-		Value: numbers[0].Value * numbers[1].Value,
-	}, nil
+	return foldNumbers("*", multiplyOp, append([]types.SketchType{&types.SketchInt{Value: 1}}, numbers...))
 }
 
+// divide divides the first number by each subsequent number. With one
+// argument, it returns its reciprocal.
+// > (/ 12 2 3)
+// 2
+// > (/ 2)
+// 1/2
 func divide(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs("/", 2, args)
+	numbers, err := validation.MinNumberArgs("/", 1, args)
 	if err != nil {
 		return nil, err
 	}
-	return &types.SketchInt{
-		Value: numbers[0].Value / numbers[1].Value,
-	}, nil
+	if len(numbers) == 1 {
+		numbers = []types.SketchType{&types.SketchInt{Value: 1}, numbers[0]}
+	}
+	return foldNumbers("/", divideOp, numbers)
 }
 
 func lt(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs("<", 2, args)
+	return compareNumbers("<", ltOp, args)
+}
+
+func lte(args ...types.SketchType) (types.SketchType, error) {
+	return compareNumbers("<=", lteOp, args)
+}
+
+func gt(args ...types.SketchType) (types.SketchType, error) {
+	return compareNumbers(">", gtOp, args)
+}
+
+func gte(args ...types.SketchType) (types.SketchType, error) {
+	return compareNumbers(">=", gteOp, args)
+}
+
+func modulo(args ...types.SketchType) (types.SketchType, error) {
+	numbers, err := validation.NNumberArgs("modulo", 2, args)
 	if err != nil {
 		return nil, err
 	}
-	return &types.SketchBoolean{
-		Value: numbers[0].Value < numbers[1].Value,
-	}, nil
+	return foldNumbers("modulo", moduloOp, numbers)
 }
 
-func lte(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs("<=", 2, args)
+// numbersEqual returns whether two numbers have the same value, regardless of
+// their type. e.g. 1 and 1.0 are equal.
+func numbersEqual(a, b types.SketchType) bool {
+	equal, err := eqOp.apply(a, b)
 	if err != nil {
-		return nil, err
+		return false
 	}
-	return &types.SketchBoolean{
-		Value: numbers[0].Value <= numbers[1].Value,
-	}, nil
+	return equal.(*types.SketchBoolean).Value
 }
 
-func gt(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs(">", 2, args)
+// float converts a number, or a string containing a number, to a float
+// > (float 1)
+// 1.0
+func float(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("float", 1, args); err != nil {
+		return nil, err
+	}
+	switch arg := args[0].(type) {
+	case *types.SketchString:
+		f, err := strconv.ParseFloat(arg.Value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &types.SketchFloat{Value: f}, nil
+	}
+
+	number, err := validation.NumberArg("float", args[0], 0)
 	if err != nil {
 		return nil, err
 	}
-	return &types.SketchBoolean{
-		Value: numbers[0].Value > numbers[1].Value,
-	}, nil
+	return &types.SketchFloat{Value: types.ToFloat64(number)}, nil
 }
 
-func gte(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs(">=", 2, args)
+// roundingFunction returns a builtin which rounds a number to an int, using
+// `round` to round floats and `roundRat` to round ratios.
+func roundingFunction(
+	fnName string, round func(float64) float64, roundRat func(*big.Rat) *big.Int,
+) func(...types.SketchType) (types.SketchType, error) {
+	return func(args ...types.SketchType) (types.SketchType, error) {
+		if err := validation.NArgs(fnName, 1, args); err != nil {
+			return nil, err
+		}
+		number, err := validation.NumberArg(fnName, args[0], 0)
+		if err != nil {
+			return nil, err
+		}
+
+		switch number := number.(type) {
+		case *types.SketchFloat:
+			return floatToInt(fnName, round(number.Value))
+		case *types.SketchRatio:
+			return types.NewBigInt(roundRat(number.Value)), nil
+		}
+		// Ints are already rounded
+		return number, nil
+	}
+}
+
+// floatToInt converts f, which must be integral, to an int. Floats too large
+// to fit in an int are converted to big ints.
+func floatToInt(fnName string, f float64) (types.SketchType, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%s: can't convert %s to an int", fnName, (&types.SketchFloat{Value: f}).String())
+	}
+	i, _ := big.NewFloat(f).Int(nil)
+	return types.NewBigInt(i), nil
+}
+
+var (
+	floor    = roundingFunction("floor", math.Floor, floorRat)
+	ceil     = roundingFunction("ceil", math.Ceil, ceilRat)
+	round    = roundingFunction("round", math.Round, roundRat)
+	truncate = roundingFunction("truncate", math.Trunc, truncateRat)
+)
+
+// truncateRat rounds r towards zero
+func truncateRat(r *big.Rat) *big.Int {
+	// Quo truncates, unlike Div
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// floorRat rounds r towards negative infinity
+func floorRat(r *big.Rat) *big.Int {
+	// Div implements Euclidean division, which rounds down, as the
+	// denominator is always positive
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+// ceilRat rounds r towards positive infinity
+func ceilRat(r *big.Rat) *big.Int {
+	return new(big.Int).Neg(floorRat(new(big.Rat).Neg(r)))
+}
+
+// roundRat rounds r to the nearest integer, rounding half away from zero,
+// like math.Round
+func roundRat(r *big.Rat) *big.Int {
+	half := big.NewRat(1, 2)
+	if r.Sign() < 0 {
+		return truncateRat(new(big.Rat).Sub(r, half))
+	}
+	return truncateRat(new(big.Rat).Add(r, half))
+}
+
+// numerator returns the numerator of a ratio or int, in lowest terms
+// > (numerator 6/4)
+// 3
+func numerator(args ...types.SketchType) (types.SketchType, error) {
+	r, err := rationalArg("numerator", args)
 	if err != nil {
 		return nil, err
 	}
-	return &types.SketchBoolean{
-		Value: numbers[0].Value >= numbers[1].Value,
-	}, nil
+	return types.NewBigInt(new(big.Int).Set(r.Num())), nil
 }
 
-func modulo(args ...types.SketchType) (types.SketchType, error) {
-	numbers, err := validation.NIntArgs("modulo", 2, args)
+// denominator returns the denominator of a ratio or int, in lowest terms
+// > (denominator 6/4)
+// 2
+func denominator(args ...types.SketchType) (types.SketchType, error) {
+	r, err := rationalArg("denominator", args)
 	if err != nil {
 		return nil, err
 	}
+	return types.NewBigInt(new(big.Int).Set(r.Denom())), nil
+}
+
+// rationalArg validates that `args` is a single int or ratio, and returns its
+// value
+func rationalArg(fnName string, args []types.SketchType) (*big.Rat, error) {
+	if err := validation.NArgs(fnName, 1, args); err != nil {
+		return nil, err
+	}
+	switch arg := args[0].(type) {
+	case *types.SketchInt, *types.SketchBigInt, *types.SketchRatio:
+		return types.ToRat(arg), nil
+	}
+	return nil, fmt.Errorf("%s: expected an int or ratio, got type %s", fnName, args[0].Type())
+}
 
-	return &types.SketchInt{
-		Value: numbers[0].Value % numbers[1].Value,
-	}, nil
+// rationalize converts a number to an exact ratio or int
+// > (rationalize 0.75)
+// 3/4
+func rationalize(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("rationalize", 1, args); err != nil {
+		return nil, err
+	}
+	number, err := validation.NumberArg("rationalize", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := number.(*types.SketchFloat)
+	if !ok {
+		return number, nil
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f.Value, 'g', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("rationalize: can't convert %s to a ratio", f.String())
+	}
+	return types.NewRatio(r), nil
 }
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
+)
+
+// ok wraps a value in an ok result
+// > (ok 1)
+// (ok 1)
+func ok(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("ok", 1, args); err != nil {
+		return nil, err
+	}
+	return &types.SketchResult{
+		Ok:    true,
+		Value: args[0],
+	}, nil
+}
+
+// sketchErr wraps a value in an error result. The value is normally a string
+// describing the error.
+// > (err "not found")
+// (err "not found")
+func sketchErr(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("err", 1, args); err != nil {
+		return nil, err
+	}
+	return &types.SketchResult{
+		Ok:    false,
+		Value: args[0],
+	}, nil
+}
+
+func isOk(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("ok?", 1, args); err != nil {
+		return nil, err
+	}
+	result, err := validation.ResultArg("ok?", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	return &types.SketchBoolean{
+		Value: result.Ok,
+	}, nil
+}
+
+// unwrap returns the value in an ok result. If it's passed an error result, it
+// raises an error, with the error result's value as the payload.
+func unwrap(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("unwrap", 1, args); err != nil {
+		return nil, err
+	}
+	result, err := validation.ResultArg("unwrap", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Ok {
+		return nil, &errors.UserError{
+			Message: fmt.Sprintf("unwrap: called with the error value %s", result),
+			Payload: result.Value,
+		}
+	}
+	return result.Value, nil
+}
+
+// unwrapOr returns the value in an ok result, or the default value supplied as
+// the second argument if it's passed an error result.
+// > (unwrap-or (err "not found") 0)
+// 0
+func unwrapOr(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("unwrap-or", 2, args); err != nil {
+		return nil, err
+	}
+	result, err := validation.ResultArg("unwrap-or", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Ok {
+		return args[1], nil
+	}
+	return result.Value, nil
+}
+
+// andThen calls the function with the value in an ok result. The function
+// must itself return a result. Error results are returned unmodified. This
+// lets you chain together a series of operations which can fail.
+// > (and-then (ok "1") try-int)
+// (ok 1)
+func andThen(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("and-then", 2, args); err != nil {
+		return nil, err
+	}
+	result, err := validation.ResultArg("and-then", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	function, err := validation.FunctionArg("and-then", args[1], 1)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Ok {
+		return result, nil
+	}
+
+	next, err := function.Call(ctx, result.Value)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := next.(*types.SketchResult); !ok {
+		return nil, fmt.Errorf("and-then: expected the function to return a result, got %s", next.Type())
+	}
+	return next, nil
+}
+
+// errResult returns an error result describing `err`.
+func errResult(err error) *types.SketchResult {
+	return &types.SketchResult{
+		Ok:    false,
+		Value: &types.SketchString{Value: err.Error()},
+	}
+}
@@ -0,0 +1,212 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
+)
+
+// set creates a set containing the items in a list, vector or set
+// > (set (list 1 2 2))
+// #{1 2}
+func set(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("set", 1, args); err != nil {
+		return nil, err
+	}
+	items, err := validation.SequenceArg("set", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewSketchSet(items)
+}
+
+func isSet(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("set?", 1, args); err != nil {
+		return nil, err
+	}
+	_, ok := args[0].(*types.SketchSet)
+	return &types.SketchBoolean{
+		Value: ok,
+	}, nil
+}
+
+// contains returns whether a set contains an item, or a hashmap contains a key
+// > (contains? #{1 2} 1)
+// true
+func contains(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("contains?", 2, args); err != nil {
+		return nil, err
+	}
+
+	switch collection := args[0].(type) {
+	case *types.SketchSet:
+		ok, err := collection.Contains(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return &types.SketchBoolean{Value: ok}, nil
+	case *types.SketchHashMap:
+		if err := types.ValidHashMapKey(args[1]); err != nil {
+			return nil, err
+		}
+		// We've validated the key, so the only error Get can return is that
+		// the key isn't in the map
+		_, err := collection.Get(args[1])
+		return &types.SketchBoolean{Value: err == nil}, nil
+	default:
+		return nil, fmt.Errorf(
+			"the function contains? expects the 1st argument `%s` to be a set or hashmap, got type %s",
+			collection, collection.Type())
+	}
+}
+
+// disj returns a new set, without the specified items
+// > (disj #{1 2 3} 1 2)
+// #{3}
+func disj(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.MinArgs("disj", 1, args); err != nil {
+		return nil, err
+	}
+	s, err := validation.SetArg("disj", args[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range args[1:] {
+		s, err = s.Remove(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// union returns a set containing every item in any of the sets
+// > (union #{1 2} #{2 3})
+// #{1 2 3}
+func union(args ...types.SketchType) (types.SketchType, error) {
+	sets, err := setArgs("union", args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the items of the other sets to the largest one, so we do as little
+	// work as possible
+	largest := 0
+	for i, s := range sets {
+		if s.Len() > sets[largest].Len() {
+			largest = i
+		}
+	}
+	result := sets[largest]
+	for i, s := range sets {
+		if i == largest {
+			continue
+		}
+		for _, item := range s.Items() {
+			result, err = result.Add(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// intersection returns a set containing the items which are in every set
+// > (intersection #{1 2} #{2 3})
+// #{2}
+func intersection(args ...types.SketchType) (types.SketchType, error) {
+	sets, err := setArgs("intersection", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := sets[0]
+	for _, item := range sets[0].Items() {
+		for _, s := range sets[1:] {
+			ok, err := s.Contains(item)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				continue
+			}
+			result, err = result.Remove(item)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+// difference returns a set containing the items in the first set which
+// aren't in any of the others
+// > (difference #{1 2 3} #{2} #{3})
+// #{1}
+func difference(args ...types.SketchType) (types.SketchType, error) {
+	sets, err := setArgs("difference", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := sets[0]
+	for _, s := range sets[1:] {
+		for _, item := range s.Items() {
+			result, err = result.Remove(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+// isSubset returns whether every item in the first set is in the second
+// > (subset? #{1} #{1 2})
+// true
+func isSubset(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("subset?", 2, args); err != nil {
+		return nil, err
+	}
+	sets, err := setArgs("subset?", args)
+	if err != nil {
+		return nil, err
+	}
+	return &types.SketchBoolean{
+		Value: subset(sets[0], sets[1]),
+	}, nil
+}
+
+// subset returns whether every item in a is in b
+func subset(a, b *types.SketchSet) bool {
+	if a.Len() > b.Len() {
+		return false
+	}
+	for _, item := range a.Items() {
+		// Items in a set are always valid, so Contains can't error
+		if ok, _ := b.Contains(item); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// setArgs validates that at least one argument was passed, and that every
+// argument is a set
+func setArgs(fnName string, args []types.SketchType) ([]*types.SketchSet, error) {
+	if err := validation.MinArgs(fnName, 1, args); err != nil {
+		return nil, err
+	}
+	sets := make([]*types.SketchSet, len(args))
+	for i, arg := range args {
+		s, err := validation.SetArg(fnName, arg, i)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = s
+	}
+	return sets, nil
+}
@@ -12,11 +12,7 @@ const SketchCode = `
   (fn
     "defn defines a function. It's equivalent to calling (def <name> (fn <...>))"
     (name a b & c)
-    (if
-      (empty? c)
-      (quasiquote (def (unquote name) (fn (unquote a) (unquote b))))
-      (quasiquote
-        (def (unquote name) (fn (unquote a) (unquote b) (unquote (first c))))))))
+    (if (empty? c) ` + "`" + `(def ~name (fn ~a ~b)) ` + "`" + `(def ~name (fn ~a ~b ~(first c))))))
 
 (defmacro
   cond
@@ -30,11 +26,7 @@ const SketchCode = `
       (> (count xs) 0)
       (let
         ((pair (first xs))) ; (prn pair)
-        (list
-          (quote if)
-          (first pair)
-          (nth pair 1)
-          (cons (quote cond) (rest xs)))))))
+        (list 'if (first pair) (nth pair 1) (cons 'cond (rest xs)))))))
 
 (defn
   not
@@ -42,9 +34,6 @@ const SketchCode = `
   (x)
   (if x false true))
 
-(defn load-file (f) (eval (read-string (+ "(do " (slurp f) "
-nil)"))))
-
 (defn
   reduce
   (function collection)
@@ -68,14 +57,11 @@ nil)"))))
     (first collection)
     (reduce (fn (a b) (if (< a b) a b)) collection)))
 
-(defn
-  hashset
-  (& items)
-  (apply hashmap (flatten (map (fn (item) (list item true)) items))))
+(defn hashset (& items) (set items))
 
-(defn hashset-get (set key) (hashmap-get set key false))
+(defn hashset-get (s key) (contains? s key))
 
-(defn hashset-add (set key) (hashmap-set set key true))
+(defn hashset-add (s key) (conj s key))
 
 (defn add1 (n) (+ n 1))
 
@@ -93,11 +79,11 @@ nil)"))))
 
 (defn
   dedupe
-  "Removes duplicate items from a list. It does this by converting it to a
-    hashmap and back, so the list can only contain items that can be a hashmap
-    key"
+  "Removes duplicate items from a list. It does this by converting it to a set
+    and back, so the list can only contain items that can be in a set. The
+    returned list is sorted"
   (l)
-  (hashmap-keys (apply hashset l)))
+  (apply list (set l)))
 
 (defn
   reverse
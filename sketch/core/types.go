@@ -1,7 +1,9 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 
 	"github.com/jamesroutley/sketch/sketch/types"
@@ -16,18 +18,66 @@ func integer(args ...types.SketchType) (types.SketchType, error) {
 	if err := validation.NArgs("int", 1, args); err != nil {
 		return nil, err
 	}
-	switch arg := args[0].(type) {
-	case *types.SketchInt:
-		return arg, nil
+	if err := convertibleToInt("int", args[0]); err != nil {
+		return nil, err
+	}
+	return toInt(args[0])
+}
+
+// tryInteger is a variant of int which returns an error result, rather than
+// raising an error, if its argument can't be converted to an int.
+func tryInteger(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("try-int", 1, args); err != nil {
+		return nil, err
+	}
+	if err := convertibleToInt("try-int", args[0]); err != nil {
+		return nil, err
+	}
+	i, err := toInt(args[0])
+	if err != nil {
+		return errResult(err), nil
+	}
+	return &types.SketchResult{
+		Ok:    true,
+		Value: i,
+	}, nil
+}
+
+// convertibleToInt returns an error if `arg` has a type which can never be
+// converted to an int.
+func convertibleToInt(fnName string, arg types.SketchType) error {
+	switch arg.(type) {
+	case *types.SketchInt, *types.SketchBigInt, *types.SketchFloat, *types.SketchRatio, *types.SketchString:
+		return nil
+	}
+	return fmt.Errorf("%s: unable to convert type %s to an int", fnName, arg.Type())
+}
+
+// toInt converts `arg`, which must be convertible to an int, to an int.
+// Floats and ratios are truncated towards zero, and strings are parsed.
+func toInt(arg types.SketchType) (types.SketchType, error) {
+	switch arg := arg.(type) {
+	case *types.SketchFloat, *types.SketchRatio:
+		return truncate(arg)
 	case *types.SketchString:
-		i, err := strconv.Atoi(arg.Value)
-		if err != nil {
-			return nil, err
-		}
-		return &types.SketchInt{
-			Value: i,
-		}, nil
-	default:
-		return nil, fmt.Errorf("int: unable to convert type %s to an int", arg.Type())
+		return parseInt(arg.Value)
+	}
+	return arg, nil
+}
+
+// parseInt parses the string `s` as an int. If the number is too large to
+// fit in an int, it's returned as a big int.
+func parseInt(s string) (types.SketchType, error) {
+	i, err := strconv.Atoi(s)
+	if err == nil {
+		return &types.SketchInt{Value: i}, nil
+	}
+	if !errors.Is(err, strconv.ErrRange) {
+		return nil, err
+	}
+	bigInt, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, err
 	}
+	return types.NewBigInt(bigInt), nil
 }
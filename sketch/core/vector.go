@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jamesroutley/sketch/sketch/limits"
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
+)
+
+// vector creates a vector containing its arguments
+// > (vector 1 2 3)
+// [1 2 3]
+func vector(args ...types.SketchType) (types.SketchType, error) {
+	return &types.SketchVector{
+		Vector: types.NewVector(args),
+	}, nil
+}
+
+func isVector(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("vector?", 1, args); err != nil {
+		return nil, err
+	}
+	_, ok := args[0].(*types.SketchVector)
+	return &types.SketchBoolean{
+		Value: ok,
+	}, nil
+}
+
+// assoc returns a new vector with the item at an index replaced, or a new
+// hashmap with a key set
+// > (assoc [1 2 3] 0 4)
+// [4 2 3]
+func assoc(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("assoc", 3, args); err != nil {
+		return nil, err
+	}
+
+	switch collection := args[0].(type) {
+	case *types.SketchHashMap:
+		return hashMapSet(args...)
+	case *types.SketchVector:
+		index, err := validation.IntArg("assoc", args[1], 1)
+		if err != nil {
+			return nil, err
+		}
+		newVector, err := collection.Vector.Assoc(index.Value, args[2])
+		if err != nil {
+			return nil, fmt.Errorf("assoc: %w", err)
+		}
+		return &types.SketchVector{
+			Vector: newVector,
+		}, nil
+	default:
+		return nil, fmt.Errorf(
+			"the function assoc expects the 1st argument `%s` to be a vector or hashmap, got type %s",
+			collection, collection.Type())
+	}
+}
+
+// conj adds items to a collection, in the way that's most efficient for the
+// collection's type: items are appended to the end of vectors, prepended to
+// the start of lists, and added to sets
+// > (conj [1 2] 3 4)
+// [1 2 3 4]
+// > (conj (list 1 2) 3 4)
+// (4 3 1 2)
+func conj(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.MinArgs("conj", 1, args); err != nil {
+		return nil, err
+	}
+
+	switch collection := args[0].(type) {
+	case *types.SketchVector:
+		newVector := collection.Vector
+		for _, item := range args[1:] {
+			newVector = newVector.Conj(item)
+		}
+		return &types.SketchVector{
+			Vector: newVector,
+		}, nil
+	case *types.SketchSet:
+		newSet := collection
+		for _, item := range args[1:] {
+			var err error
+			newSet, err = newSet.Add(item)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return newSet, nil
+	case *types.SketchList:
+		if err := limits.Allocate(ctx, len(args)-1); err != nil {
+			return nil, err
+		}
+		newList := collection.List
+		for _, item := range args[1:] {
+			newList = newList.Conj(item)
+		}
+		return &types.SketchList{
+			List: newList,
+		}, nil
+	default:
+		return nil, fmt.Errorf(
+			"the function conj expects the 1st argument `%s` to be a vector, list or set, got type %s",
+			collection, collection.Type())
+	}
+}
@@ -8,19 +8,34 @@ package environment
 
 import (
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/jamesroutley/sketch/sketch/types"
 )
 
+// Env is safe for concurrent use. Functions run in parallel, e.g. by pmap, can
+// look up symbols in the environments they share, and bind new ones. Each
+// binding is atomic: a concurrent lookup sees either the old value or the new
+// one, and if several goroutines bind the same symbol at once, the last one to
+// do so wins.
 type Env struct {
 	Outer *Env
-	Data  map[string]types.SketchType
+	mu    sync.RWMutex
+	data  map[string]types.SketchType
+	// Capabilities is the set of capabilities code evaluated in the
+	// environment is allowed to use. It's only meaningful on root
+	// environments - use Root to find the set which applies to an
+	// environment
+	Capabilities types.Capability
 }
 
+// NewEnv returns a new root environment, which grants every capability.
 func NewEnv() *Env {
 	return &Env{
-		Outer: nil,
-		Data:  map[string]types.SketchType{},
+		Outer:        nil,
+		data:         map[string]types.SketchType{},
+		Capabilities: types.AllCapabilities,
 	}
 }
 
@@ -30,7 +45,7 @@ func NewEnv() *Env {
 func NewFunctionEnv(parent *Env, parameters []*types.SketchSymbol, arguments []types.SketchType) (*Env, error) {
 	env := &Env{
 		Outer: parent,
-		Data:  map[string]types.SketchType{},
+		data:  map[string]types.SketchType{},
 	}
 
 	if err := validateBindList(parameters, arguments); err != nil {
@@ -92,46 +107,86 @@ func validateBindList(parameters []*types.SketchSymbol, arguments []types.Sketch
 	return nil
 }
 
+// Set binds `key` to `value` in this environment.
 func (e *Env) Set(key string, value types.SketchType) {
-	e.Data[key] = value
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.data[key] = value
+}
+
+// lookup returns the value bound to `key` in this environment, ignoring its
+// outer environments.
+func (e *Env) lookup(key string) (types.SketchType, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	value, ok := e.data[key]
+	return value, ok
 }
 
 // TODO: would be nice to switch this to return `comma ok`, rather than an error
 func (e *Env) Find(key string) (types.EnvType, error) {
-	if e == nil {
-		return nil, fmt.Errorf("`%s` is undefined", key)
-	}
-	if _, ok := e.Data[key]; ok {
-		return e, nil
+	for env := e; env != nil; env = env.Outer {
+		if _, ok := env.lookup(key); ok {
+			return env, nil
+		}
 	}
-	return e.Outer.Find(key)
+	return nil, fmt.Errorf("`%s` is undefined", key)
 }
 
+// Get returns the value bound to `key` in this environment or the nearest of
+// its outer environments which binds it.
 func (e *Env) Get(key string) (types.SketchType, error) {
-	env, err := e.Find(key)
-	if err != nil {
-		return nil, err
+	for env := e; env != nil; env = env.Outer {
+		if value, ok := env.lookup(key); ok {
+			return value, nil
+		}
 	}
+	return nil, fmt.Errorf("`%s` is undefined", key)
+}
 
-	value, ok := env.(*Env).Data[key]
-	if !ok {
-		panic("item not found in environment")
-	}
-	// If the symbol evaluated to a function, bind the symbol's value to
-	// it, so we can use it in stack traces later.
-	// TODO: I suspect this isn't treadsafe, because we're mutating the
-	// function object
-	function, ok := value.(*types.SketchFunction)
-	if ok {
-		function.BoundName = key
-		return function, nil
+// Bindings returns a copy of the symbols bound in this environment, ignoring
+// its outer environments.
+func (e *Env) Bindings() map[string]types.SketchType {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	bindings := make(map[string]types.SketchType, len(e.data))
+	for key, value := range e.data {
+		bindings[key] = value
 	}
-	return value, nil
+	return bindings
 }
 
 func (e *Env) ChildEnv() *Env {
 	return &Env{
 		Outer: e,
-		Data:  map[string]types.SketchType{},
+		data:  map[string]types.SketchType{},
+	}
+}
+
+// Root returns the root environment `e` is nested in, which is `e` itself if
+// it doesn't have an outer environment.
+func (e *Env) Root() *Env {
+	root := e
+	for root.Outer != nil {
+		root = root.Outer
+	}
+	return root
+}
+
+// Symbols returns every symbol bound in this environment or any of its outer
+// environments, sorted alphabetically.
+func (e *Env) Symbols() []string {
+	seen := map[string]bool{}
+	var symbols []string
+	for env := e; env != nil; env = env.Outer {
+		for key := range env.Bindings() {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			symbols = append(symbols, key)
+		}
 	}
+	sort.Strings(symbols)
+	return symbols
 }
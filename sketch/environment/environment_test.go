@@ -0,0 +1,58 @@
+package environment
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnv_ConcurrentSetAndGet(t *testing.T) {
+	root := NewEnv()
+	root.Set("shared", &types.SketchInt{Value: 0})
+	child := root.ChildEnv()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", i)
+			root.Set(key, &types.SketchInt{Value: i})
+			root.Set("shared", &types.SketchInt{Value: i})
+
+			value, err := child.Get(key)
+			assert.NoError(t, err)
+			assert.Equal(t, &types.SketchInt{Value: i}, value)
+			_, err = child.Get("shared")
+			assert.NoError(t, err)
+			child.Symbols()
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, root.Bindings(), 21)
+}
+
+func TestEnv_Get(t *testing.T) {
+	root := NewEnv()
+	root.Set("a", &types.SketchInt{Value: 1})
+	child := root.ChildEnv()
+	child.Set("b", &types.SketchInt{Value: 2})
+
+	value, err := child.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, &types.SketchInt{Value: 1}, value)
+
+	_, err = root.Get("b")
+	assert.EqualError(t, err, "`b` is undefined")
+
+	env, err := child.Find("a")
+	require.NoError(t, err)
+	assert.Same(t, root, env)
+
+	assert.Equal(t, map[string]types.SketchType{"b": &types.SketchInt{Value: 2}}, child.Bindings())
+}
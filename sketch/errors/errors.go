@@ -6,28 +6,173 @@
 // This error is maybe closer to an Exception type like in Python?
 package errors
 
-// type StackFrame struct {
-// 	// Name of the function which pushed this f
-// 	FunctionName string
-// }
+import (
+	"errors"
+	"fmt"
+	"io"
 
+	"github.com/jamesroutley/sketch/sketch/types"
+)
+
+// Error is an error which happened while evaluating Sketch code. It records
+// where in the source code the error happened, and the call stack which led
+// to it.
 type Error struct {
-	Err   error
-	Stack []string
+	Err error
+	// Stack is the function calls which were in progress when the error
+	// happened
+	Stack *types.CallStack
+	// Pos is the position in the source code of the innermost form which was
+	// being evaluated when the error happened
+	Pos types.Position
 }
 
 func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Err)
+	}
 	return e.Err.Error()
 }
 
-func Wrap(err error, stack []string) error {
-	xerr, ok := err.(*Error)
-	if ok {
-		// already wrapped - append to the stack
-		xerr.Stack = append(xerr.Stack, stack...)
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap adds `frames`, innermost first, to the call stack of `err`, outside
+// the frames it already has. If `err` doesn't yet know where in the source
+// code it happened, `pos` is recorded as its position. Nil frames are
+// skipped.
+//
+// `err` isn't modified - if it's already an *Error, a copy with the extra
+// frames is returned. This matters for exceptions which are caught and
+// rethrown, which share the original error. If `err` wraps an *Error, e.g.
+// because a Go function called Sketch code and wrapped the error it returned,
+// the call stack of the wrapped error is kept.
+func Wrap(err error, pos types.Position, frames ...*types.StackFrame) error {
+	var inner *Error
+	if !errors.As(err, &inner) {
+		return &Error{
+			Err:   err,
+			Stack: push(nil, frames),
+			Pos:   pos,
+		}
 	}
+
+	if err == error(inner) {
+		if inner.Pos.IsValid() && !hasFrames(frames) {
+			return err
+		}
+		wrapped := &Error{
+			Err:   inner.Err,
+			Stack: push(inner.Stack, frames),
+			Pos:   inner.Pos,
+		}
+		if !wrapped.Pos.IsValid() {
+			wrapped.Pos = pos
+		}
+		return wrapped
+	}
+
 	return &Error{
 		Err:   err,
-		Stack: stack,
+		Stack: push(inner.Stack, frames),
+		Pos:   pos,
+	}
+}
+
+// push returns `stack` with `frames`, innermost first, pushed on to it.
+func push(stack *types.CallStack, frames []*types.StackFrame) *types.CallStack {
+	if stack == nil {
+		stack = types.NewCallStack()
+	}
+	for _, frame := range frames {
+		if frame != nil {
+			stack = stack.Push(frame)
+		}
+	}
+	return stack
+}
+
+func hasFrames(frames []*types.StackFrame) bool {
+	for _, frame := range frames {
+		if frame != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Fprint writes `err` to `w`. If it's an *Error with a call stack, it's
+// followed by the call stack which led to it, innermost call last. Runs of
+// calls made by the same form, such as deep recursion, are collapsed into a
+// single entry.
+func Fprint(w io.Writer, err error) {
+	var xerr *Error
+	var frames []*types.StackFrame
+	if errors.As(err, &xerr) {
+		frames = xerr.Stack.Frames()
+	}
+	if len(frames) == 0 {
+		fmt.Fprintln(w, err)
+		return
 	}
+
+	fmt.Fprintln(w, err)
+	fmt.Fprintf(w, "\nCall stack:\n")
+	for i := 0; i < len(frames); {
+		frame := frames[i]
+		repeats := 1
+		for i+repeats < len(frames) && sameCall(frame, frames[i+repeats]) {
+			repeats++
+		}
+
+		fmt.Fprintln(w, "  ", frame)
+		if repeats > 1 {
+			fmt.Fprintf(w, "   ... %s called %d times\n", frame.FunctionName, repeats)
+		}
+		i += repeats
+	}
+}
+
+// sameCall returns whether two frames are calls to the same function, made by
+// the same form.
+func sameCall(a, b *types.StackFrame) bool {
+	return a.FunctionName == b.FunctionName && a.Pos == b.Pos
+}
+
+// UserError is an error raised by Sketch code with the `error` or `throw`
+// functions. As well as a message, it carries a payload, which can be any
+// Sketch value.
+type UserError struct {
+	Message string
+	Payload types.SketchType
+}
+
+func (e *UserError) Error() string {
+	return e.Message
+}
+
+// ToException converts `err` into a value which can be handled by Sketch code.
+func ToException(err error) *types.SketchException {
+	exception := &types.SketchException{
+		Message: err.Error(),
+		Payload: &types.SketchNil{},
+		Err:     err,
+	}
+
+	var xerr *Error
+	if errors.As(err, &xerr) {
+		exception.Stack = xerr.Stack
+		// The message shouldn't include the position - that's available in
+		// the call stack
+		exception.Message = xerr.Err.Error()
+	}
+
+	var userErr *UserError
+	if errors.As(err, &userErr) {
+		exception.Message = userErr.Message
+		exception.Payload = userErr.Payload
+	}
+
+	return exception
 }
@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func frameNames(err error) []string {
+	var xerr *Error
+	if !errors.As(err, &xerr) {
+		return nil
+	}
+	var names []string
+	for _, frame := range xerr.Stack.Frames() {
+		names = append(names, frame.FunctionName)
+	}
+	return names
+}
+
+func TestWrap(t *testing.T) {
+	pos := types.Position{Line: 2, Column: 5}
+	err := Wrap(fmt.Errorf("oh no"), pos, types.NewStackFrame("inner", nil, pos))
+	wrapped := Wrap(err, types.Position{Line: 1, Column: 1}, types.NewStackFrame("outer", nil, pos))
+
+	assert.Equal(t, "2:5: oh no", wrapped.Error())
+	assert.Equal(t, []string{"outer", "inner"}, frameNames(wrapped))
+	// The original error isn't changed, so exceptions which are rethrown
+	// keep their call stack
+	assert.Equal(t, []string{"inner"}, frameNames(err))
+}
+
+func TestWrap_WrappedError(t *testing.T) {
+	pos := types.Position{Line: 2, Column: 5}
+	err := Wrap(fmt.Errorf("oh no"), pos, types.NewStackFrame("inner", nil, pos))
+	// e.g. a Go function which calls Sketch code, and wraps its error
+	err = fmt.Errorf("calling callback: %w", err)
+
+	wrapped := Wrap(err, types.Position{Line: 1, Column: 1}, types.NewStackFrame("outer", nil, pos))
+	assert.Equal(t, []string{"outer", "inner"}, frameNames(wrapped))
+	assert.True(t, errors.Is(wrapped, err))
+}
+
+func TestWrap_NoFrames(t *testing.T) {
+	pos := types.Position{Line: 2, Column: 5}
+	err := Wrap(fmt.Errorf("oh no"), pos)
+	assert.Empty(t, frameNames(err))
+	assert.Same(t, err, Wrap(err, types.Position{Line: 1, Column: 1}))
+}
+
+func TestToException(t *testing.T) {
+	pos := types.Position{Line: 2, Column: 5}
+	err := Wrap(&UserError{Message: "oh no", Payload: &types.SketchNil{}}, pos, types.NewStackFrame("f", nil, pos))
+
+	exception := ToException(err)
+	assert.Equal(t, "oh no", exception.Message)
+	frames := exception.Stack.Frames()
+	require.Len(t, frames, 1)
+	assert.Equal(t, "f", frames[0].FunctionName)
+}
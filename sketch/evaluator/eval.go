@@ -2,45 +2,129 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/jamesroutley/sketch/sketch/core"
 	"github.com/jamesroutley/sketch/sketch/environment"
 	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/limits"
 	"github.com/jamesroutley/sketch/sketch/reader"
 	"github.com/jamesroutley/sketch/sketch/types"
 )
 
 // RootEnvironment initialises a root environment loaded with all the built in
 // functions and variables defined in the core package. This environment is
-// used as the context in which Sketch code is evaluated.
+// used as the context in which Sketch code is evaluated. Its IO functions use
+// the process's standard streams.
 func RootEnvironment() (*environment.Env, error) {
+	return NewRootEnvironment(core.StandardIO())
+}
+
+// NewRootEnvironment is like RootEnvironment, but its IO functions, such as
+// prn, use the streams in `streams`.
+func NewRootEnvironment(streams *core.IO) (*environment.Env, error) {
+	return NewSandboxedRootEnvironment(streams, types.AllCapabilities)
+}
+
+// NewSandboxedRootEnvironment is like NewRootEnvironment, but code evaluated
+// in it can only use the capabilities in `capabilities`. Builtins which need
+// other capabilities, such as slurp, return a *types.PermissionError when
+// they're called, as does importing a module which needs them. Code can't
+// escape the sandbox with eval, or by importing modules, because they're
+// evaluated with the same capabilities.
+func NewSandboxedRootEnvironment(streams *core.IO, capabilities types.Capability) (*environment.Env, error) {
+	return newRootEnvironment(core.IOFunctions(streams), capabilities)
+}
+
+// newRootEnvironment initialises a root environment, using `ioFunctions` as
+// its IO functions.
+func newRootEnvironment(
+	ioFunctions map[string]types.SketchType, capabilities types.Capability,
+) (*environment.Env, error) {
 	env := environment.NewEnv()
+	env.Capabilities = capabilities
 	for key, value := range core.EnvironmentItems {
+		if missing := capabilities.Missing(core.BuiltinCapabilities[key]); missing != 0 {
+			value = deniedFunction(key, missing)
+		}
 		env.Set(key, value)
 	}
+	for key, value := range ioFunctions {
+		env.Set(key, value)
+	}
+	if missing := capabilities.Missing(types.ReadFiles); missing != 0 {
+		env.Set("load-file", deniedFunction("load-file", missing))
+	} else {
+		env.Set("load-file", loadFile(env))
+	}
 
 	if core.SketchCode == "" {
 		return env, nil
 	}
-	ast, err := reader.Read(fmt.Sprintf("(do %s)", core.SketchCode))
-	if err != nil {
-		return nil, err
-	}
-	_, err = Eval(ast, env)
-	if err != nil {
+	decoder := reader.NewDecoder(strings.NewReader(core.SketchCode), "core")
+	if _, err := EvalProgram(context.Background(), decoder, env); err != nil {
 		return nil, err
 	}
 
 	return env, nil
 }
 
+// deniedFunction returns a function which stands in for the builtin `name`
+// in a sandboxed environment which doesn't grant the capabilities in
+// `missing`. Calling it returns a permission error, which is clearer than the
+// builtin being undefined.
+func deniedFunction(name string, missing types.Capability) *types.SketchFunction {
+	return &types.SketchFunction{
+		Func: func(args ...types.SketchType) (types.SketchType, error) {
+			return nil, &types.PermissionError{Name: name, Missing: missing}
+		},
+		BoundName: name,
+	}
+}
+
 func Evaluate(ast types.SketchType) (types.SketchType, error) {
 	env, err := RootEnvironment()
 	if err != nil {
 		return nil, err
 	}
-	return Eval(ast, env)
+	return Eval(context.Background(), ast, env)
+}
+
+// EvalProgram reads the top level forms from `decoder` one at a time,
+// evaluating each in `env` before reading the next, so the program doesn't
+// need to be in memory all at once. Returns the value of the last form, or nil
+// if there are none. Each form is evaluated with `ctx`, like Eval.
+//
+// Evaluation stops at the first form with a syntax error. The rest of the
+// program is still read, so the returned reader.ParseErrors lists every
+// syntax error from that form on.
+func EvalProgram(ctx context.Context, decoder *reader.Decoder, env *environment.Env) (types.SketchType, error) {
+	var evaluated types.SketchType = &types.SketchNil{}
+	for {
+		form, err := decoder.Decode()
+		if err == io.EOF {
+			return evaluated, nil
+		}
+		if parseErrs, ok := err.(reader.ParseErrors); ok {
+			if _, err := decoder.DecodeAll(); err != nil {
+				if rest, ok := err.(reader.ParseErrors); ok {
+					parseErrs = append(parseErrs, rest...)
+				}
+			}
+			return nil, parseErrs
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		evaluated, err = Eval(ctx, form, env)
+		if err != nil {
+			return nil, err
+		}
+	}
 }
 
 // Eval evaulates a piece of parsed code.
@@ -54,22 +138,55 @@ func Evaluate(ast types.SketchType) (types.SketchType, error) {
 // 3. Lists: by default, they're treated as function calls - each item is
 // evaluated, and the first item (the function itself) is called with the rest
 // of the items as arguments.
+//
+// Evaluation stops with a *limits.Error if `ctx` is cancelled, or if it
+// exceeds any of the limits `ctx` carries (see limits.NewContext).
 func Eval(
-	ast types.SketchType, env *environment.Env,
+	ctx context.Context, ast types.SketchType, env *environment.Env,
 ) (evaluatedAST types.SketchType, err error) {
-	callStack := []string{}
+	// call is the frame of the non tail call optimised function this Eval
+	// called last, and tailCall the frame of the tail call optimised function
+	// whose body it's evaluating. If an error happens, they're added to its
+	// call stack. Each tail call replaces the previous tail call's frame,
+	// counting the frames which are elided.
+	var call, tailCall *types.StackFrame
+	// pos is the position of the form currently being evaluated. We track it
+	// so errors can report where in the source code they happened. Forms
+	// generated by macros don't have a position - in that case, we keep the
+	// position of the form which was expanded.
+	pos := types.PositionOf(ast)
+	// We look these up once, rather than on every step, so checking them in
+	// the loop below is cheap. The tracker is nil if there aren't any limits
+	done := ctx.Done()
+	tracker := limits.From(ctx)
 	// Wrap any errors returned with the call stack
-	// TODO: this is kinda gross
 	defer func() {
+		tracker.Exit()
 		if err != nil {
-			err = errors.Wrap(err, callStack)
+			err = errors.Wrap(err, pos, call, tailCall)
 		}
 	}()
+	if err := tracker.Enter(); err != nil {
+		return nil, err
+	}
 	// This whlie loop enables tail call optimisation (TCO), where we mutate
 	// `ast` and `env` and jump back to the top, rather than recursively
 	// calling `Eval`. This stops a stack frame from being pushed, and lets us
 	// recurse to depths that would otherwise cause a stack overflow.
 	for {
+		if p := types.PositionOf(ast); p.IsValid() {
+			pos = p
+		}
+
+		select {
+		case <-done:
+			return nil, limits.Check(ctx)
+		default:
+		}
+		if err := tracker.Step(); err != nil {
+			return nil, err
+		}
+
 		// First - check if ast is a list. If it isn't we can evaluate it as an
 		// atom and return.
 		// N.B: a lot of mutation goes on in this function. We use these scoping
@@ -77,7 +194,7 @@ func Eval(
 		{
 			list, ok := ast.(*types.SketchList)
 			if !ok {
-				return evalAST(ast, env)
+				return evalAST(ctx, ast, env)
 			}
 
 			// If the list is empty - return it. Empty lists eval to themselves
@@ -98,7 +215,7 @@ func Eval(
 		// First, macros. A macro modifies Lisp source code, so we need to
 		// expand them before we continue evaluating.
 		{
-			expandedAST, err := macroExpand(ast, env)
+			expandedAST, err := macroExpand(ctx, ast, env)
 			if err != nil {
 				return nil, err
 			}
@@ -111,7 +228,7 @@ func Eval(
 				ast = expandedAST
 				// continue
 			default:
-				return evalAST(expandedAST, env)
+				return evalAST(ctx, expandedAST, env)
 			}
 		}
 
@@ -121,7 +238,7 @@ func Eval(
 		// false. Instead of recusively calling Eval, they return a new `ast`
 		// and `env`, and we loop back to the top of this function.
 		{
-			evaluated, newAST, newEnv, err := evalTCOSpecialForm(ast, env)
+			evaluated, newAST, newEnv, err := evalTCOSpecialForm(ctx, ast, env)
 			if err != nil {
 				return nil, err
 			}
@@ -135,7 +252,7 @@ func Eval(
 
 		// Process non tail call optimised special forms
 		{
-			evaluated, newAST, err := evalSpecialForm(ast, env)
+			evaluated, newAST, err := evalSpecialForm(ctx, ast, env)
 			if err != nil {
 				return nil, err
 			}
@@ -155,7 +272,7 @@ func Eval(
 		// lazy). Not evaluating them up front would give us a lazy language,
 		// which has interesting and different properties.
 		{
-			newAST, err := evalAST(ast, env)
+			newAST, err := evalAST(ctx, ast, env)
 			if err != nil {
 				return nil, err
 			}
@@ -165,6 +282,11 @@ func Eval(
 				return nil, fmt.Errorf("list did not evaluate to a list")
 			}
 
+			// Keywords can be called to look themselves up in a hash map
+			if keyword, ok := list.List.First().(*types.SketchKeyword); ok {
+				return keyword.Call(list.List.Rest().ToSlice())
+			}
+
 			function, ok := list.List.First().(*types.SketchFunction)
 			if !ok {
 				return nil, fmt.Errorf(
@@ -173,22 +295,23 @@ func Eval(
 				)
 			}
 
+			args := list.List.Rest().ToSlice()
 			if !function.TailCallOptimised {
-				// TODO: once we've got real stack frames, mention that this is
-				// no TCO
-				callStack = append(callStack, function.BoundName)
-				newAST, err := function.Func(list.List.Rest().ToSlice()...)
-				if err != nil {
-					return nil, errors.Wrap(err, callStack)
-				}
-				return newAST, nil
+				call = types.NewStackFrame(function.BoundName, args, pos)
+				return function.Call(ctx, args...)
 			}
 
-			// Function is tail call optimised.
+			// Function is tail call optimised. Its frame replaces the frame
+			// of the function which made the tail call, if there was one
+			frame := types.NewStackFrame(function.BoundName, args, pos)
+			if tailCall != nil {
+				frame.TailCalls = tailCall.TailCalls + 1
+			}
+			tailCall = frame
+
 			// Construct the correct environment it should be run in
 			childEnv, err := environment.NewFunctionEnv(
-				function.Env.(*environment.Env), function.Params,
-				list.List.Rest().ToSlice(),
+				function.Env.(*environment.Env), function.Params, args,
 			)
 			if err != nil {
 				return nil, fmt.Errorf("error calling '%s': %w", function.BoundName, err)
@@ -197,8 +320,6 @@ func Eval(
 			// TCO
 			ast = function.AST
 			env = childEnv
-			// TODO: once we've got real stack frames, mention that this is TCO
-			callStack = append(callStack, function.BoundName)
 			continue
 		}
 	}
@@ -207,7 +328,7 @@ func Eval(
 // evalAST implements the evaluation rules for normal expressions. Any special
 // cases are handed above us, in the Eval function. This function is an
 // implementation detail of Eval, and shoulnd't be called apart from by it.
-func evalAST(ast types.SketchType, env *environment.Env) (types.SketchType, error) {
+func evalAST(ctx context.Context, ast types.SketchType, env *environment.Env) (types.SketchType, error) {
 	switch tok := ast.(type) {
 	case *types.SketchSymbol:
 		value, err := env.Get(tok.Value)
@@ -219,7 +340,7 @@ func evalAST(ast types.SketchType, env *environment.Env) (types.SketchType, erro
 		items := tok.List.ToSlice()
 		newItems := make([]types.SketchType, len(items))
 		for i, item := range items {
-			evaluated, err := Eval(item, env)
+			evaluated, err := Eval(ctx, item, env)
 			if err != nil {
 				return nil, err
 			}
@@ -228,6 +349,28 @@ func evalAST(ast types.SketchType, env *environment.Env) (types.SketchType, erro
 		return &types.SketchList{
 			List: types.NewList(newItems),
 		}, nil
+	case *types.SketchVector:
+		items := tok.Vector.ToSlice()
+		for i, item := range items {
+			evaluated, err := Eval(ctx, item, env)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = evaluated
+		}
+		return &types.SketchVector{
+			Vector: types.NewVector(items),
+		}, nil
+	case *types.SketchSet:
+		items := tok.Items()
+		for i, item := range items {
+			evaluated, err := Eval(ctx, item, env)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = evaluated
+		}
+		return types.NewSketchSet(items)
 	case *types.SketchHashMap:
 		keys := tok.Keys()
 		items := make([]types.SketchType, 0, len(keys)*2)
@@ -237,12 +380,15 @@ func evalAST(ast types.SketchType, env *environment.Env) (types.SketchType, erro
 				// This is bad
 				panic(err)
 			}
-			evaluated, err := Eval(value, env)
+			evaluated, err := Eval(ctx, value, env)
 			if err != nil {
 				return nil, err
 			}
 			items = append(items, key, evaluated)
 		}
+		if tok.Ordered() {
+			return types.NewOrderedSketchHashMap(items)
+		}
 		return types.NewSketchHashMap(items)
 	}
 	return ast, nil
@@ -1,6 +1,8 @@
 package evaluator
 
 import (
+	"context"
+
 	"github.com/jamesroutley/sketch/sketch/environment"
 	"github.com/jamesroutley/sketch/sketch/types"
 )
@@ -117,7 +119,7 @@ func isMacroCall(ast types.SketchType, env *environment.Env) bool {
 	return function.IsMacro
 }
 
-func macroExpand(ast types.SketchType, env *environment.Env) (types.SketchType, error) {
+func macroExpand(ctx context.Context, ast types.SketchType, env *environment.Env) (types.SketchType, error) {
 	for isMacroCall(ast, env) {
 		// TODO: isMacroCall could return the macro function, which would save
 		// the casting below
@@ -136,7 +138,7 @@ func macroExpand(ast types.SketchType, env *environment.Env) (types.SketchType,
 		}
 		macroFunc := macroNameValue.(*types.SketchFunction)
 
-		newAst, err := macroFunc.Func(list.List.Rest().ToSlice()...)
+		newAst, err := macroFunc.Call(ctx, list.List.Rest().ToSlice()...)
 		if err != nil {
 			return nil, err
 		}
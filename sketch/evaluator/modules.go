@@ -1,48 +1,74 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/jamesroutley/sketch/sketch/core"
+	"github.com/jamesroutley/sketch/sketch/environment"
 	"github.com/jamesroutley/sketch/sketch/reader"
 	"github.com/jamesroutley/sketch/sketch/stdlib/file"
 	"github.com/jamesroutley/sketch/sketch/stdlib/queue"
 	"github.com/jamesroutley/sketch/sketch/stdlib/regex"
 	"github.com/jamesroutley/sketch/sketch/stdlib/str"
 	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
 )
 
 type registeredModule struct {
 	EnvironmentItems map[string]types.SketchType
 	SketchCode       string
+	// Capabilities are the capabilities the module's functions need. A
+	// sandboxed environment can only import the module if it grants them
+	Capabilities types.Capability
 }
 
 var registeredModules = map[string]*registeredModule{}
 
-func registerModule(name string, items map[string]types.SketchType, code string) {
+func registerModule(name string, items map[string]types.SketchType, code string, capabilities types.Capability) {
 	registeredModules[name] = &registeredModule{
 		EnvironmentItems: items,
 		SketchCode:       code,
+		Capabilities:     capabilities,
 	}
 }
 
 func init() {
-	registerModule("string", str.EnvironmentItems, str.SketchCode)
-	registerModule("file", file.EnvironmentItems, file.SketchCode)
-	registerModule("queue", map[string]types.SketchType{}, queue.SketchCode)
-	registerModule("regex", regex.EnvironmentItems, regex.SketchCode)
+	registerModule("string", str.EnvironmentItems, str.SketchCode, types.NoCapabilities)
+	registerModule("file", file.EnvironmentItems, file.SketchCode, types.ReadFiles)
+	registerModule("queue", map[string]types.SketchType{}, queue.SketchCode, types.NoCapabilities)
+	registerModule("regex", regex.EnvironmentItems, regex.SketchCode, types.NoCapabilities)
 }
 
-func loadStdlibModule(name string) (*types.SketchModule, error) {
+// moduleEnvironment returns the root environment a module imported from `env`
+// is evaluated in. The module shares the IO functions and capabilities of the
+// program that imported it, so its output goes to the same place, and it
+// can't do anything the program couldn't.
+func moduleEnvironment(env *environment.Env) (*environment.Env, error) {
+	root := env.Root()
+
+	ioFunctions := make(map[string]types.SketchType, len(core.IONames))
+	for _, name := range core.IONames {
+		if value, err := root.Get(name); err == nil {
+			ioFunctions[name] = value
+		}
+	}
+	return newRootEnvironment(ioFunctions, root.Capabilities)
+}
+
+func loadStdlibModule(ctx context.Context, name string, importer *environment.Env) (*types.SketchModule, error) {
 	rawModule, ok := registeredModules[name]
 	if !ok {
 		return nil, fmt.Errorf("could not find stdlib module %s", name)
 	}
+	if missing := importer.Root().Capabilities.Missing(rawModule.Capabilities); missing != 0 {
+		return nil, &types.PermissionError{Name: fmt.Sprintf("the module %s", name), Missing: missing}
+	}
 
-	env, err := RootEnvironment()
+	env, err := moduleEnvironment(importer)
 	if err != nil {
 		return nil, err
 	}
@@ -65,17 +91,11 @@ func loadStdlibModule(name string) (*types.SketchModule, error) {
 		}, nil
 	}
 	// Pull the exported module from any Sketch code
-	ast, err := reader.Read(fmt.Sprintf("(do %s)", rawModule.SketchCode))
+	decoder := reader.NewDecoder(strings.NewReader(rawModule.SketchCode), name)
+	evaluated, err := EvalProgram(ctx, decoder, env)
 	if err != nil {
 		return nil, err
 	}
-	evaluated, err := Eval(ast, env)
-	if err != nil {
-		if err.Error() == "read comment" {
-			return nil, fmt.Errorf("to be importable, %s must end in an `export-as` statement", name)
-		}
-		return nil, err
-	}
 
 	module, ok := evaluated.(*types.SketchModule)
 	if !ok {
@@ -86,9 +106,12 @@ func loadStdlibModule(name string) (*types.SketchModule, error) {
 	return module, nil
 }
 
-func importModule(path string) (*types.SketchModule, error) {
+func importModule(ctx context.Context, path string, importer *environment.Env) (*types.SketchModule, error) {
 	if _, ok := registeredModules[path]; ok {
-		return loadStdlibModule(path)
+		return loadStdlibModule(ctx, path, importer)
+	}
+	if missing := importer.Root().Capabilities.Missing(types.ImportModules); missing != 0 {
+		return nil, &types.PermissionError{Name: fmt.Sprintf("importing %s", path), Missing: missing}
 	}
 
 	goPath := os.Getenv("GOPATH")
@@ -98,25 +121,19 @@ func importModule(path string) (*types.SketchModule, error) {
 
 	fullPath := filepath.Join(goPath, "src", path)
 
-	moduleEnv, err := RootEnvironment()
+	moduleEnv, err := moduleEnvironment(importer)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := ioutil.ReadFile(fullPath)
+	f, err := os.Open(fullPath)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	ast, err := reader.Read(fmt.Sprintf(`(do %s)`, data))
-	if err != nil {
-		return nil, err
-	}
-	evaluated, err := Eval(ast, moduleEnv)
+	evaluated, err := EvalProgram(ctx, reader.NewDecoder(f, path), moduleEnv)
 	if err != nil {
-		if err.Error() == "read comment" {
-			return nil, fmt.Errorf("to be importable, %s must end in an `export-as` statement", path)
-		}
 		return nil, err
 	}
 
@@ -127,3 +144,41 @@ func importModule(path string) (*types.SketchModule, error) {
 
 	return module, nil
 }
+
+// loadFile returns the load-file builtin of the root environment `env`.
+// load-file evaluates each form in a file in `env`, like the REPL's :load, so
+// the file's definitions are visible to the program which loaded it. The file
+// is streamed through the reader, so positions in errors point into it.
+func loadFile(env *environment.Env) *types.SketchFunction {
+	load := func(ctx context.Context, args ...types.SketchType) (types.SketchType, error) {
+		if err := validation.NoErrorArgs("load-file", args); err != nil {
+			return nil, err
+		}
+		if err := validation.NArgs("load-file", 1, args); err != nil {
+			return nil, err
+		}
+		path, err := validation.StringArg("load-file", args[0], 0)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(path.Value)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		if _, err := EvalProgram(ctx, reader.NewDecoder(f, path.Value), env); err != nil {
+			return nil, err
+		}
+		return &types.SketchNil{}, nil
+	}
+
+	return &types.SketchFunction{
+		FuncContext: load,
+		Func: func(args ...types.SketchType) (types.SketchType, error) {
+			return load(context.Background(), args...)
+		},
+		BoundName: "load-file",
+	}
+}
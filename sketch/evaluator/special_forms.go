@@ -1,19 +1,31 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jamesroutley/sketch/sketch/environment"
+	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/limits"
 	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/jamesroutley/sketch/sketch/validation"
 )
 
 type specialFormEvaluator func(
-	operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+	ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error)
 
+// SpecialForms lists the names of Sketch's special forms, including the tail
+// call optimised ones. They aren't bound in any environment, so the REPL uses
+// this list to tab complete them.
+var SpecialForms = []string{
+	"def", "defmacro", "do", "eval", "export-as", "fn", "if", "import", "let",
+	"macroexpand", "module-lookup", "quasiquote", "quasiquoteexpand", "quote",
+	"try", "with-parallelism",
+}
+
 func evalSpecialForm(
-	ast types.SketchType, env *environment.Env,
+	ctx context.Context, ast types.SketchType, env *environment.Env,
 ) (evaluated bool, newAST types.SketchType, err error) {
 	tok, ok := ast.(*types.SketchList)
 	if !ok {
@@ -51,12 +63,16 @@ func evalSpecialForm(
 		evaluator = evalExportAs
 	case "module-lookup":
 		evaluator = evalModuleLookup
+	case "try":
+		evaluator = evalTry
+	case "with-parallelism":
+		evaluator = evalWithParallelism
 
 	default:
 		return false, nil, nil
 	}
 
-	newAST, err = evaluator(operator, args, env)
+	newAST, err = evaluator(ctx, operator, args, env)
 	return true, newAST, err
 }
 
@@ -67,7 +83,7 @@ func evalSpecialForm(
 // #<function>
 // > (add1 2)
 // 3
-func evalFn(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalFn(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if numArgs := len(args); numArgs != 2 && numArgs != 3 {
 		return nil, fmt.Errorf("fn statements must have two or three arguments, got %d", numArgs)
@@ -108,6 +124,16 @@ func evalFn(operator *types.SketchSymbol, args []types.SketchType, env *environm
 		binds[i] = bind
 	}
 
+	call := func(ctx context.Context, exprs ...types.SketchType) (types.SketchType, error) {
+		childEnv, err := environment.NewFunctionEnv(
+			env, binds, exprs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return Eval(ctx, args[1], childEnv)
+	}
+
 	return &types.SketchFunction{
 		// All functions are by default tail call optimised. This means,
 		// instead of calling this object's Func() method (which recursively
@@ -124,20 +150,34 @@ func evalFn(operator *types.SketchSymbol, args []types.SketchType, env *environm
 		// execute a function from the function type itself. We do this in the
 		// stdlib function `map`, where we want to execute the passed in
 		// function, but don't have access to the Eval loop to tail call
-		// optimise it.
+		// optimise it. It's passed the context of the evaluation calling it,
+		// so the function's body is evaluated with that evaluation's limits.
+		FuncContext: call,
 		Func: func(exprs ...types.SketchType) (types.SketchType, error) {
-			childEnv, err := environment.NewFunctionEnv(
-				env, binds, exprs,
-			)
-			if err != nil {
-				return nil, err
-			}
-			return Eval(args[1], childEnv)
+			return call(context.Background(), exprs...)
 		},
-		BoundName: "anonymous function",
+		BoundName: anonymousFunctionName,
 	}, nil
 }
 
+// anonymousFunctionName is the name of functions created by fn, until they're
+// bound to a symbol.
+const anonymousFunctionName = "anonymous function"
+
+// nameFunction records `name` as the name of `value`, if it's a function which
+// hasn't been bound to a symbol yet. The name is used in stack traces.
+// Functions can be shared between goroutines, so rather than changing the
+// function, it returns a copy.
+func nameFunction(value types.SketchType, name string) types.SketchType {
+	function, ok := value.(*types.SketchFunction)
+	if !ok || function.BoundName != anonymousFunctionName {
+		return value
+	}
+	named := *function
+	named.BoundName = name
+	return &named
+}
+
 // Assigns a value to a symbol in the current environment
 // e.g:
 //
@@ -145,7 +185,7 @@ func evalFn(operator *types.SketchSymbol, args []types.SketchType, env *environm
 // 10
 // > a
 // 10
-func evalDef(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalDef(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("def", 2, args); err != nil {
 		return nil, err
@@ -154,15 +194,16 @@ func evalDef(operator *types.SketchSymbol, args []types.SketchType, env *environ
 	if err != nil {
 		return nil, err
 	}
-	value, err := Eval(args[1], env)
+	value, err := Eval(ctx, args[1], env)
 	if err != nil {
 		return nil, err
 	}
+	value = nameFunction(value, key.Value)
 	env.Set(key.Value, value)
 	return value, nil
 }
 
-func evalQuote(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalQuote(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("quote", 1, args); err != nil {
 		return nil, err
@@ -172,7 +213,7 @@ func evalQuote(operator *types.SketchSymbol, args []types.SketchType, env *envir
 
 // evalQuasiquoteExpand evaluates the `quasiquoteexpand` macro.
 // This macro is used to test the internal implementation of quasiquote
-func evalQuasiquoteExpand(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalQuasiquoteExpand(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("quasiquoteexpand", 1, args); err != nil {
 		return nil, err
@@ -181,7 +222,7 @@ func evalQuasiquoteExpand(operator *types.SketchSymbol, args []types.SketchType,
 }
 
 // Creates a new macro
-func evalDefmacro(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalDefmacro(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("defmacro", 2, args); err != nil {
 		return nil, err
@@ -190,7 +231,7 @@ func evalDefmacro(operator *types.SketchSymbol, args []types.SketchType, env *en
 	if err != nil {
 		return nil, err
 	}
-	value, err := Eval(args[1], env)
+	value, err := Eval(ctx, args[1], env)
 	if err != nil {
 		return nil, err
 	}
@@ -198,21 +239,27 @@ func evalDefmacro(operator *types.SketchSymbol, args []types.SketchType, env *en
 	if err != nil {
 		return nil, err
 	}
-	function.IsMacro = true
-	env.Set(key.Value, function)
-	return function, nil
+	// Mark a copy of the function as a macro, so the function itself can
+	// still be called normally
+	macro := *function
+	macro.IsMacro = true
+	if macro.BoundName == anonymousFunctionName {
+		macro.BoundName = key.Value
+	}
+	env.Set(key.Value, &macro)
+	return &macro, nil
 }
 
-func evalMacroexpand(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalMacroexpand(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("macroexpand", 1, args); err != nil {
 		return nil, err
 	}
-	return macroExpand(args[0], env)
+	return macroExpand(ctx, args[0], env)
 }
 
 // evalImport imports a module.
-func evalImport(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalImport(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("import", 1, args); err != nil {
 		return nil, err
@@ -222,7 +269,7 @@ func evalImport(operator *types.SketchSymbol, args []types.SketchType, env *envi
 		return nil, err
 	}
 
-	module, err := importModule(relativePath.Value)
+	module, err := importModule(ctx, relativePath.Value, env)
 	if err != nil {
 		return nil, err
 	}
@@ -232,7 +279,7 @@ func evalImport(operator *types.SketchSymbol, args []types.SketchType, env *envi
 	return module, nil
 }
 
-func evalExportAs(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalExportAs(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("export-as", 2, args); err != nil {
 		return nil, err
@@ -279,7 +326,7 @@ func evalExportAs(operator *types.SketchSymbol, args []types.SketchType, env *en
 	return module, nil
 }
 
-func evalModuleLookup(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalModuleLookup(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, err error) {
 	if err := validation.NArgs("module-lookup", 2, args); err != nil {
 		return nil, err
@@ -306,3 +353,100 @@ func evalModuleLookup(operator *types.SketchSymbol, args []types.SketchType, env
 
 	return m.Environment.Get(valueName.Value)
 }
+
+// evalTry evaluates the `try` special form. It evaluates its first argument.
+// If that raises an error, and a `catch` clause is supplied, the error is
+// bound to the symbol given in the catch clause, and the clause's body is
+// evaluated instead. If a `finally` clause is supplied, its body is always
+// evaluated last, and its value discarded.
+//
+// e.g:
+// > (try (nth (list) 1) (catch e (exception-message e)) (finally (prn "done")))
+// "done"
+// "nth: index out of range - 1, with length 0, []"
+func evalTry(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+) (newAST types.SketchType, err error) {
+	if numArgs := len(args); numArgs != 2 && numArgs != 3 {
+		return nil, fmt.Errorf("try statements must have two or three arguments, got %d", numArgs)
+	}
+
+	var catchSymbol *types.SketchSymbol
+	var catchBody, finallyBody types.SketchType
+	for i, arg := range args[1:] {
+		// The clauses start at the 2nd argument
+		position := i + 2
+		clause, ok := arg.(*types.SketchList)
+		if !ok {
+			return nil, fmt.Errorf("try: the %s argument should be a catch or finally clause, got %s", validation.ToOrdinal(position), arg.Type())
+		}
+		items := clause.List.ToSlice()
+		if len(items) == 0 {
+			return nil, fmt.Errorf("try: the %s argument should be a catch or finally clause, got ()", validation.ToOrdinal(position))
+		}
+		clauseType, ok := items[0].(*types.SketchSymbol)
+		if !ok {
+			return nil, fmt.Errorf("try: the %s argument should be a catch or finally clause, got %s", validation.ToOrdinal(position), clause)
+		}
+
+		switch {
+		case clauseType.Value == "catch" && catchBody == nil && finallyBody == nil:
+			if err := validation.NArgs("catch", 2, items[1:]); err != nil {
+				return nil, err
+			}
+			catchSymbol, err = validation.SymbolArg("catch", items[1], 0)
+			if err != nil {
+				return nil, err
+			}
+			catchBody = items[2]
+		case clauseType.Value == "finally" && finallyBody == nil:
+			if err := validation.NArgs("finally", 1, items[1:]); err != nil {
+				return nil, err
+			}
+			finallyBody = items[1]
+		default:
+			return nil, fmt.Errorf("try: expected at most one catch clause, followed by at most one finally clause, got %s", clause)
+		}
+	}
+
+	result, err := Eval(ctx, args[0], env)
+	if err != nil && catchBody != nil {
+		catchEnv := env.ChildEnv()
+		catchEnv.Set(catchSymbol.Value, errors.ToException(err))
+		result, err = Eval(ctx, catchBody, catchEnv)
+	}
+
+	if finallyBody != nil {
+		if _, err := Eval(ctx, finallyBody, env); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, err
+}
+
+// evalWithParallelism evaluates its body with the parallelism set to its
+// first argument, so pmap and pfilter, including any called by functions
+// the body calls, use at most that many goroutines.
+//
+// > (with-parallelism 1 (pmap (fn (x) (prn x)) (list 1 2)))
+// 1
+// 2
+// (nil nil)
+func evalWithParallelism(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+) (newAST types.SketchType, err error) {
+	if err := validation.NArgs("with-parallelism", 2, args); err != nil {
+		return nil, err
+	}
+	value, err := Eval(ctx, args[0], env)
+	if err != nil {
+		return nil, err
+	}
+	n, err := validation.IntArg("with-parallelism", value, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n.Value < 1 {
+		return nil, fmt.Errorf("with-parallelism: the parallelism must be at least 1, got %d", n.Value)
+	}
+	return Eval(limits.WithParallelism(ctx, n.Value), args[1], env)
+}
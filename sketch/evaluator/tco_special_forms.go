@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/jamesroutley/sketch/sketch/core"
@@ -13,7 +14,7 @@ import (
 // possible we can remove it, but it might come in useful when we eventually
 // print where in the source code an error comes from.
 type tcoSpecialFormEvaluator func(
-	operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+	ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, newEnv *environment.Env, err error)
 
 // Some special forms end in an evaluation. We could implement this by
@@ -23,7 +24,7 @@ type tcoSpecialFormEvaluator func(
 // to invaluate it in. Eval loops back to the beginning of the function and
 // re-runs itself using these new params.
 func evalTCOSpecialForm(
-	ast types.SketchType, env *environment.Env,
+	ctx context.Context, ast types.SketchType, env *environment.Env,
 ) (evaluated bool, newAST types.SketchType, newEnv *environment.Env, err error) {
 	tok, ok := ast.(*types.SketchList)
 	if !ok {
@@ -58,7 +59,7 @@ func evalTCOSpecialForm(
 		return false, nil, nil, nil
 	}
 
-	newAST, newEnv, err = evaluator(operator, args, env)
+	newAST, newEnv, err = evaluator(ctx, operator, args, env)
 	return true, newAST, newEnv, err
 }
 
@@ -70,7 +71,7 @@ func evalTCOSpecialForm(
 // > (let ((a 1) (b (+ a 1))) b)
 // 2 ; a == b, b == a+1 == 2
 func evalLet(
-	operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+	ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, newEnv *environment.Env, err error) {
 	if err := validation.NArgs("let", 2, args); err != nil {
 		return nil, nil, err
@@ -103,11 +104,11 @@ func evalLet(
 		if !ok {
 			return nil, nil, fmt.Errorf("let: the %s binding list item's first arg isn't a symbol", validation.ToOrdinal(i))
 		}
-		value, err := Eval(pair.List.Rest().First(), childEnv)
+		value, err := Eval(ctx, pair.List.Rest().First(), childEnv)
 		if err != nil {
 			return nil, nil, err
 		}
-		childEnv.Set(key.Value, value)
+		childEnv.Set(key.Value, nameFunction(value, key.Value))
 	}
 
 	// childEnv := env.ChildEnv()
@@ -133,10 +134,10 @@ func evalLet(
 // For TCO, we eval all but the last argument here, then return the last
 // argument to be evaluated in the main Eval loop.
 func evalDo(
-	operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+	ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, newEnv *environment.Env, err error) {
 	for _, arg := range args[:len(args)-1] {
-		if _, err := Eval(arg, env); err != nil {
+		if _, err := Eval(ctx, arg, env); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -148,12 +149,12 @@ func evalDo(
 // to be evaluated. If it is, return the third param to be evaluated, or
 // `nil` if none is supplied. If none is supplied, the `nil` value is
 // evalulated, but just evaluates to `nil`.
-func evalIf(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalIf(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, newEnv *environment.Env, err error) {
 	if numArgs := len(args); numArgs != 2 && numArgs != 3 {
 		return nil, nil, fmt.Errorf("if statements must have two or three arguments, got %d", numArgs)
 	}
-	condition, err := Eval(args[0], env)
+	condition, err := Eval(ctx, args[0], env)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -168,7 +169,7 @@ func evalIf(operator *types.SketchSymbol, args []types.SketchType, env *environm
 	return &types.SketchNil{}, env, nil
 }
 
-func evalQuasiquote(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalQuasiquote(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, newEnv *environment.Env, err error) {
 	ast, err := quasiquote(args[0])
 	if err != nil {
@@ -177,11 +178,11 @@ func evalQuasiquote(operator *types.SketchSymbol, args []types.SketchType, env *
 	return ast, env, nil
 }
 
-func evalEval(operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
+func evalEval(ctx context.Context, operator *types.SketchSymbol, args []types.SketchType, env *environment.Env,
 ) (newAST types.SketchType, newEnv *environment.Env, err error) {
 	// First evaluate the call to eval's arguments (just like a normal function
 	// call)
-	evaluated, err := Eval(args[0], env)
+	evaluated, err := Eval(ctx, args[0], env)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,176 @@
+package sketch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jamesroutley/sketch/sketch/core"
+	"github.com/jamesroutley/sketch/sketch/environment"
+	"github.com/jamesroutley/sketch/sketch/evaluator"
+	"github.com/jamesroutley/sketch/sketch/limits"
+	"github.com/jamesroutley/sketch/sketch/reader"
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/jamesroutley/sketch/sketch/validation"
+)
+
+// Interpreter evaluates Sketch code for a Go program which embeds Sketch.
+// Definitions made by code it evaluates persist, so later code can use them.
+type Interpreter struct {
+	env         *environment.Env
+	limits      limits.Limits
+	parallelism int
+}
+
+// Option configures an Interpreter.
+type Option func(*config)
+
+type config struct {
+	streams      *core.IO
+	capabilities types.Capability
+	limits       limits.Limits
+	parallelism  int
+}
+
+// WithStdout sets where Sketch code's output, such as from prn, is written.
+// It defaults to os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(c *config) {
+		c.streams.Stdout = w
+	}
+}
+
+// WithStderr sets where Sketch code's error output, such as from eprn, is
+// written. It defaults to os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(c *config) {
+		c.streams.Stderr = w
+	}
+}
+
+// WithStdin sets where Sketch code's input, such as from read-line, is read
+// from. It defaults to os.Stdin.
+func WithStdin(r io.Reader) Option {
+	return func(c *config) {
+		c.streams.Stdin = r
+	}
+}
+
+// WithCapabilities sandboxes the interpreter, so the code it evaluates can
+// only use the capabilities in `capabilities`. Use types.NoCapabilities to
+// evaluate untrusted code which shouldn't be able to read files or import
+// modules from disk. By default, code can use every capability.
+func WithCapabilities(capabilities types.Capability) Option {
+	return func(c *config) {
+		c.capabilities = capabilities
+	}
+}
+
+// WithLimits limits how much work each evaluation, such as each call to
+// EvalString, can do. An evaluation which exceeds them returns a
+// *limits.Error. By default, evaluations aren't limited.
+func WithLimits(l limits.Limits) Option {
+	return func(c *config) {
+		c.limits = l
+	}
+}
+
+// WithParallelism sets the number of goroutines builtins which call functions
+// in parallel, such as pmap and pfilter, can use. It defaults to
+// runtime.GOMAXPROCS.
+func WithParallelism(n int) Option {
+	return func(c *config) {
+		c.parallelism = n
+	}
+}
+
+// New returns an interpreter whose environment contains Sketch's builtins.
+func New(opts ...Option) (*Interpreter, error) {
+	c := &config{
+		streams:      core.StandardIO(),
+		capabilities: types.AllCapabilities,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	root, err := evaluator.NewSandboxedRootEnvironment(c.streams, c.capabilities)
+	if err != nil {
+		return nil, err
+	}
+	// Definitions go in a child environment, so they can shadow builtins
+	// without changing them
+	return &Interpreter{env: root.ChildEnv(), limits: c.limits, parallelism: c.parallelism}, nil
+}
+
+// EvalString evaluates the Sketch code in `code`, and returns the value of its
+// last form.
+func (i *Interpreter) EvalString(code string) (types.SketchType, error) {
+	return i.EvalStringContext(context.Background(), code)
+}
+
+// EvalStringContext is like EvalString, but stops evaluating the code if
+// `ctx` is cancelled.
+func (i *Interpreter) EvalStringContext(ctx context.Context, code string) (types.SketchType, error) {
+	return i.EvalReaderContext(ctx, strings.NewReader(code), "")
+}
+
+// EvalReader evaluates the Sketch code read from `r`, and returns the value of
+// its last form. The positions in any errors refer to `filename`.
+func (i *Interpreter) EvalReader(r io.Reader, filename string) (types.SketchType, error) {
+	return i.EvalReaderContext(context.Background(), r, filename)
+}
+
+// EvalReaderContext is like EvalReader, but stops evaluating the code if
+// `ctx` is cancelled.
+func (i *Interpreter) EvalReaderContext(ctx context.Context, r io.Reader, filename string) (types.SketchType, error) {
+	return evaluator.EvalProgram(i.evalContext(ctx), reader.NewDecoder(r, filename), i.env)
+}
+
+// Call calls the Sketch function bound to `name` with `args`, and returns its
+// result.
+func (i *Interpreter) Call(name string, args ...types.SketchType) (types.SketchType, error) {
+	return i.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is like Call, but stops evaluating the function if `ctx` is
+// cancelled.
+func (i *Interpreter) CallContext(ctx context.Context, name string, args ...types.SketchType) (types.SketchType, error) {
+	value, err := i.env.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	function, ok := value.(*types.SketchFunction)
+	if !ok {
+		return nil, fmt.Errorf("cannot call `%s`: it is a %s, not a function", name, value.Type())
+	}
+	return function.Call(i.evalContext(ctx), args...)
+}
+
+// evalContext returns the context to evaluate code with, which carries the
+// interpreter's limits and parallelism. Each evaluation gets a new budget.
+func (i *Interpreter) evalContext(ctx context.Context) context.Context {
+	if i.parallelism > 0 {
+		ctx = limits.WithParallelism(ctx, i.parallelism)
+	}
+	if i.limits == (limits.Limits{}) {
+		return ctx
+	}
+	return limits.NewContext(ctx, i.limits)
+}
+
+// Define binds `name` to `value`, so Sketch code evaluated afterwards can use
+// it.
+func (i *Interpreter) Define(name string, value types.SketchType) {
+	i.env.Set(name, value)
+}
+
+// RegisterFunc binds `name` to a Sketch function which calls `f`. Like the
+// builtins, the function returns an error if it's passed an error result.
+func (i *Interpreter) RegisterFunc(name string, f func(...types.SketchType) (types.SketchType, error)) {
+	i.Define(name, &types.SketchFunction{
+		Func:      validation.RejectErrorArgs(name, f),
+		BoundName: name,
+	})
+}
@@ -0,0 +1,393 @@
+package sketch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jamesroutley/sketch/sketch/limits"
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterpreter_EvalString(t *testing.T) {
+	interpreter, err := New()
+	require.NoError(t, err)
+
+	_, err = interpreter.EvalString("(defn add (a b) (+ a b))")
+	require.NoError(t, err)
+
+	// Definitions persist between calls
+	result, err := interpreter.EvalString("(add 1 2) (add 3 4)")
+	require.NoError(t, err)
+	assert.Equal(t, "7", result.String())
+
+	_, err = interpreter.EvalString("(add 1")
+	assert.Error(t, err)
+}
+
+func TestInterpreter_EvalReader(t *testing.T) {
+	interpreter, err := New()
+	require.NoError(t, err)
+
+	result, err := interpreter.EvalReader(strings.NewReader("(def x 2)\n(* x 21)"), "test.skt")
+	require.NoError(t, err)
+	assert.Equal(t, "42", result.String())
+
+	_, err = interpreter.EvalReader(strings.NewReader("(undefined-function)"), "test.skt")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test.skt:1:2")
+}
+
+func TestInterpreter_LoadFile(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.skt")
+	require.NoError(t, os.WriteFile(lib, []byte("(defn double (x) (* x 2))\n(def four (double 2))"), 0o644))
+	broken := filepath.Join(dir, "broken.skt")
+	require.NoError(t, os.WriteFile(broken, []byte("(def x 1)\n(undefined-function)"), 0o644))
+	unbalanced := filepath.Join(dir, "unbalanced.skt")
+	require.NoError(t, os.WriteFile(unbalanced, []byte("(def y 1))\n(def z 2)\n(def w \"\\q\")"), 0o644))
+
+	interpreter, err := New()
+	require.NoError(t, err)
+
+	// The file's definitions are visible after it's loaded
+	result, err := interpreter.EvalString(fmt.Sprintf("(load-file %q) (double four)", lib))
+	require.NoError(t, err)
+	assert.Equal(t, "8", result.String())
+
+	// Errors point into the loaded file
+	_, err = interpreter.EvalString(fmt.Sprintf("(load-file %q)", broken))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), broken+":2:2")
+
+	// Forms are evaluated as they're read, up to the first syntax error. Every
+	// syntax error in the file is reported
+	_, err = interpreter.EvalString(fmt.Sprintf("(load-file %q)", unbalanced))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), unbalanced+":1:10: unexpected `)`")
+	assert.Contains(t, err.Error(), unbalanced+":3:8: invalid escape sequence in string: \\q")
+	result, err = interpreter.EvalString("y")
+	require.NoError(t, err)
+	assert.Equal(t, "1", result.String())
+	_, err = interpreter.EvalString("z")
+	assert.Error(t, err)
+}
+
+func TestInterpreter_Call(t *testing.T) {
+	interpreter, err := New()
+	require.NoError(t, err)
+
+	_, err = interpreter.EvalString("(defn greet (name) (+ \"hello \" name))")
+	require.NoError(t, err)
+
+	result, err := interpreter.Call("greet", &types.SketchString{Value: "world"})
+	require.NoError(t, err)
+	assert.Equal(t, &types.SketchString{Value: "hello world"}, result)
+
+	// Builtins can be called too
+	result, err = interpreter.Call("+", &types.SketchInt{Value: 1}, &types.SketchInt{Value: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "3", result.String())
+
+	_, err = interpreter.Call("undefined-function")
+	assert.Error(t, err)
+
+	interpreter.Define("not-a-function", &types.SketchInt{Value: 1})
+	_, err = interpreter.Call("not-a-function")
+	assert.Error(t, err)
+}
+
+func TestInterpreter_Define(t *testing.T) {
+	interpreter, err := New()
+	require.NoError(t, err)
+
+	interpreter.Define("answer", &types.SketchInt{Value: 42})
+	result, err := interpreter.EvalString("(+ answer 1)")
+	require.NoError(t, err)
+	assert.Equal(t, "43", result.String())
+}
+
+func TestInterpreter_RegisterFunc(t *testing.T) {
+	interpreter, err := New()
+	require.NoError(t, err)
+
+	interpreter.RegisterFunc("shout", func(args ...types.SketchType) (types.SketchType, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("shout takes 1 argument, got %d", len(args))
+		}
+		return &types.SketchString{Value: strings.ToUpper(args[0].(*types.SketchString).Value)}, nil
+	})
+
+	result, err := interpreter.EvalString(`(shout "hi")`)
+	require.NoError(t, err)
+	assert.Equal(t, &types.SketchString{Value: "HI"}, result)
+
+	_, err = interpreter.EvalString(`(shout)`)
+	assert.Error(t, err)
+}
+
+func TestInterpreter_IO(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	interpreter, err := New(
+		WithStdout(&stdout),
+		WithStderr(&stderr),
+		WithStdin(strings.NewReader("first\nsecond")),
+	)
+	require.NoError(t, err)
+
+	result, err := interpreter.EvalString(`
+(prn "out" 1)
+(eprn "err")
+(prn (read-line) (read-line) (read-line))`)
+	require.NoError(t, err)
+	assert.Equal(t, "nil", result.String())
+	assert.Equal(t, "\"out\" 1\n\"first\" \"second\" nil\n", stdout.String())
+	assert.Equal(t, "\"err\"\n", stderr.String())
+}
+
+func TestInterpreter_IOInImportedModules(t *testing.T) {
+	goPath := t.TempDir()
+	t.Setenv("GOPATH", goPath)
+	modulePath := filepath.Join(goPath, "src", "greeter.skt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(modulePath), 0o755))
+	require.NoError(t, os.WriteFile(modulePath, []byte(`
+(defn greet () (prn "hello"))
+(export-as greeter (greet))`), 0o644))
+
+	var stdout bytes.Buffer
+	interpreter, err := New(WithStdout(&stdout))
+	require.NoError(t, err)
+
+	_, err = interpreter.EvalString(`(import "greeter.skt") (greeter.greet)`)
+	require.NoError(t, err)
+	assert.Equal(t, "\"hello\"\n", stdout.String())
+}
+
+func TestInterpreter_Sandbox(t *testing.T) {
+	dir := t.TempDir()
+	secretPath := filepath.Join(dir, "secret.txt")
+	require.NoError(t, os.WriteFile(secretPath, []byte("secret"), 0o644))
+	t.Setenv("GOPATH", dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "src"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "src", "reader.skt"), []byte(fmt.Sprintf(`
+(def contents (slurp %q))
+(export-as reader (contents))`, secretPath)), 0o644))
+
+	cases := []struct {
+		name         string
+		capabilities types.Capability
+		code         string
+		allowed      bool
+	}{
+		{"slurp", types.NoCapabilities, fmt.Sprintf("(slurp %q)", secretPath), false},
+		{"slurp with read-files", types.ReadFiles, fmt.Sprintf("(slurp %q)", secretPath), true},
+		{"load-file", types.NoCapabilities, fmt.Sprintf("(load-file %q)", secretPath), false},
+		{"slurp in eval", types.NoCapabilities, fmt.Sprintf("(eval '(slurp %q))", secretPath), false},
+		{"stdlib module without capabilities", types.NoCapabilities, `(import "string")`, true},
+		{"stdlib module with capabilities", types.NoCapabilities, `(import "file")`, false},
+		{"stdlib module with read-files", types.ReadFiles, `(import "file")`, true},
+		{"module from disk", types.NoCapabilities, `(import "reader.skt")`, false},
+		{"module from disk which reads a file", types.ImportModules, `(import "reader.skt")`, false},
+		{"module from disk with read-files", types.ImportModules | types.ReadFiles, `(import "reader.skt")`, true},
+		{"pure code", types.NoCapabilities, `(map (fn (x) (* x 2)) [1 2 3])`, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			interpreter, err := New(WithCapabilities(tc.capabilities))
+			require.NoError(t, err)
+
+			_, err = interpreter.EvalString(tc.code)
+			if tc.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			var permissionError *types.PermissionError
+			assert.ErrorAs(t, err, &permissionError)
+		})
+	}
+}
+
+func TestInterpreter_Limits(t *testing.T) {
+	cases := []struct {
+		name     string
+		limits   limits.Limits
+		code     string
+		expected limits.Kind
+	}{
+		{
+			"infinite tail recursion",
+			limits.Limits{MaxSteps: 10000},
+			"(defn f () (f)) (f)",
+			limits.StepLimit,
+		},
+		{
+			"deep recursion",
+			limits.Limits{MaxDepth: 200},
+			"(defn f (n) (+ 1 (f (- n 1)))) (f 1000)",
+			limits.DepthLimit,
+		},
+		{
+			"big list",
+			limits.Limits{MaxConsCells: 1000},
+			"(range 2000)",
+			limits.ConsCellLimit,
+		},
+		{
+			"flatten",
+			limits.Limits{MaxConsCells: 1000},
+			"(count (flatten (list (range 0 600) (range 0 300))))",
+			limits.ConsCellLimit,
+		},
+		{
+			"hashmap-keys",
+			limits.Limits{MaxConsCells: 1000},
+			"(hashmap-keys (apply hashmap (range 800)))",
+			limits.ConsCellLimit,
+		},
+		{
+			"hashmap-values",
+			limits.Limits{MaxConsCells: 1000},
+			"(hashmap-values (apply hashmap (range 800)))",
+			limits.ConsCellLimit,
+		},
+		{
+			"string-to-list",
+			limits.Limits{MaxConsCells: 1000},
+			`(string-to-list (apply + (map (fn (x) "ab") (range 0 300))))`,
+			limits.ConsCellLimit,
+		},
+		{
+			"read-string",
+			limits.Limits{MaxConsCells: 1000},
+			`(read-string (pr-str (range 0 600)))`,
+			limits.ConsCellLimit,
+		},
+		{
+			"list built with cons",
+			limits.Limits{MaxConsCells: 1000},
+			"(defn build (n acc) (if (= n 0) acc (build (- n 1) (cons n acc)))) (build 2000 ())",
+			limits.ConsCellLimit,
+		},
+		{
+			"infinite loop in map",
+			limits.Limits{MaxSteps: 10000},
+			"(defn f (x) (f x)) (map f [1 2 3])",
+			limits.StepLimit,
+		},
+		{
+			"catching the error doesn't escape the limit",
+			limits.Limits{MaxSteps: 10000},
+			"(defn f () (f)) (try (f) (catch e (f)))",
+			limits.StepLimit,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			interpreter, err := New(WithLimits(tc.limits))
+			require.NoError(t, err)
+
+			_, err = interpreter.EvalString(tc.code)
+			var limitErr *limits.Error
+			require.ErrorAs(t, err, &limitErr)
+			assert.Equal(t, tc.expected, limitErr.Kind)
+		})
+	}
+}
+
+func TestInterpreter_LimitsApplyToEachEvaluation(t *testing.T) {
+	interpreter, err := New(WithLimits(limits.Limits{MaxConsCells: 100}))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := interpreter.EvalString("(range 60)")
+		require.NoError(t, err)
+	}
+}
+
+func TestInterpreter_Cancellation(t *testing.T) {
+	interpreter, err := New()
+	require.NoError(t, err)
+	_, err = interpreter.EvalString("(defn loop (x) (loop x))")
+	require.NoError(t, err)
+
+	for _, code := range []string{"(loop 1)", "(map loop [1 2 3])", "(filter loop (list 1 2 3))"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		_, err = interpreter.EvalStringContext(ctx, code)
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded, code)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = interpreter.CallContext(ctx, "loop", &types.SketchInt{Value: 1})
+	var limitErr *limits.Error
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, limits.Cancelled, limitErr.Kind)
+}
+
+func TestInterpreter_MapIsSequential(t *testing.T) {
+	var stdout bytes.Buffer
+	interpreter, err := New(WithStdout(&stdout))
+	require.NoError(t, err)
+
+	_, err = interpreter.EvalString(`(map prn (range 20)) (filter prn (range 20))`)
+	require.NoError(t, err)
+
+	var expected strings.Builder
+	for i := 0; i < 2; i++ {
+		for n := 0; n < 20; n++ {
+			fmt.Fprintln(&expected, n)
+		}
+	}
+	assert.Equal(t, expected.String(), stdout.String())
+}
+
+func TestInterpreter_Parallelism(t *testing.T) {
+	cases := []struct {
+		name     string
+		opts     []Option
+		code     string
+		expected int64
+	}{
+		{"option", []Option{WithParallelism(3)}, "(pmap track (range 50))", 3},
+		{"special form", nil, "(with-parallelism 2 (pfilter track (range 50)))", 2},
+		{"special form overrides the option", []Option{WithParallelism(3)}, "(with-parallelism 1 (pmap track (range 50)))", 1},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			interpreter, err := New(tc.opts...)
+			require.NoError(t, err)
+
+			// track records the most calls which were in progress at once
+			var running, maxRunning int64
+			interpreter.RegisterFunc("track", func(args ...types.SketchType) (types.SketchType, error) {
+				n := atomic.AddInt64(&running, 1)
+				defer atomic.AddInt64(&running, -1)
+				for {
+					max := atomic.LoadInt64(&maxRunning)
+					if n <= max || atomic.CompareAndSwapInt64(&maxRunning, max, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				return args[0], nil
+			})
+
+			_, err = interpreter.EvalString(tc.code)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, atomic.LoadInt64(&maxRunning), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,203 @@
+// Package limits implements limits on how much work evaluating Sketch code
+// can do. Limits are attached to the context.Context passed to
+// evaluator.Eval, which checks them as it evaluates code, along with whether
+// the context has been cancelled. They let a host stop untrusted code which
+// runs forever, recurses too deeply or allocates too much. The context also
+// carries how many goroutines builtins such as pmap can use.
+package limits
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+)
+
+// Limits configures the limits on an evaluation. A limit of 0 means there's no
+// limit.
+type Limits struct {
+	// MaxSteps is the number of evaluation steps the evaluation can take.
+	// Each time Eval evaluates a form, including each iteration of a tail
+	// call optimised loop, is a step
+	MaxSteps int64
+	// MaxDepth is how deeply calls to Eval can nest. Tail calls don't nest,
+	// but every other function call, and evaluating a function's arguments,
+	// does. Setting it stops deep recursion from overflowing the Go stack
+	MaxDepth int
+	// MaxConsCells is the number of list cells the evaluation can allocate,
+	// with builtins such as list, cons, concat, range, map, flatten,
+	// hashmap-keys and read-string
+	MaxConsCells int64
+}
+
+// Kind describes which limit an Error is about.
+type Kind int
+
+const (
+	// StepLimit is reported when an evaluation takes more than MaxSteps
+	// steps
+	StepLimit Kind = iota
+	// DepthLimit is reported when Eval calls nest more than MaxDepth deep
+	DepthLimit
+	// ConsCellLimit is reported when an evaluation allocates more than
+	// MaxConsCells list cells
+	ConsCellLimit
+	// Cancelled is reported when the evaluation's context is cancelled, or
+	// its deadline passes
+	Cancelled
+)
+
+// Error is returned when an evaluation hits one of its limits, or is
+// cancelled. Like other errors, it can be caught by Sketch code with try, but
+// because the limit stays exceeded, evaluating anything else fails again.
+type Error struct {
+	Kind Kind
+	// Limit is the value of the limit which was exceeded
+	Limit int64
+	// Err is the context's error, for errors of kind Cancelled
+	Err error
+}
+
+func (e *Error) Error() string {
+	switch e.Kind {
+	case StepLimit:
+		return fmt.Sprintf("evaluation exceeded its limit of %d steps", e.Limit)
+	case DepthLimit:
+		return fmt.Sprintf("evaluation exceeded its maximum depth of %d", e.Limit)
+	case ConsCellLimit:
+		return fmt.Sprintf("evaluation exceeded its limit of %d cons cells", e.Limit)
+	}
+	return fmt.Sprintf("evaluation cancelled: %s", e.Err)
+}
+
+// Unwrap returns the context's error for cancelled evaluations, so errors.Is
+// can be used to check for context.DeadlineExceeded.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// budget is the amount of work an evaluation has done. It's shared by every
+// goroutine taking part in the evaluation.
+type budget struct {
+	limits    Limits
+	steps     int64
+	consCells int64
+}
+
+// Tracker tracks an evaluation's progress against its limits. Each goroutine
+// taking part in an evaluation has its own Tracker, which records how deeply
+// nested its calls to Eval are, and shares the evaluation's step and cons
+// cell budget with the others.
+//
+// A nil *Tracker, which is returned by From if the context doesn't have any
+// limits, doesn't enforce any.
+type Tracker struct {
+	budget *budget
+	depth  int
+}
+
+type trackerKey struct{}
+
+// NewContext returns a copy of `ctx` which carries `limits`. Evaluations which
+// use the context share the same budget, so should be given their own
+// context.
+func NewContext(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, trackerKey{}, &Tracker{
+		budget: &budget{limits: limits},
+	})
+}
+
+// From returns the Tracker carried by `ctx`, or nil if it doesn't carry any
+// limits.
+func From(ctx context.Context) *Tracker {
+	tracker, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return tracker
+}
+
+// Fork returns a copy of `ctx` to use in a new goroutine which takes part in
+// the same evaluation, such as the ones pmap runs functions in. The goroutine
+// shares the evaluation's budget, but tracks its own depth.
+func Fork(ctx context.Context) context.Context {
+	tracker := From(ctx)
+	if tracker == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, trackerKey{}, &Tracker{
+		budget: tracker.budget,
+		depth:  tracker.depth,
+	})
+}
+
+// Step records an evaluation step, and returns an error if the evaluation has
+// taken too many.
+func (t *Tracker) Step() error {
+	if t == nil || t.budget.limits.MaxSteps == 0 {
+		return nil
+	}
+	if atomic.AddInt64(&t.budget.steps, 1) > t.budget.limits.MaxSteps {
+		return &Error{Kind: StepLimit, Limit: t.budget.limits.MaxSteps}
+	}
+	return nil
+}
+
+// Enter records that a call to Eval has started, and returns an error if calls
+// are nested too deeply. Every call to Enter must be matched by a call to
+// Exit, even if it returns an error.
+func (t *Tracker) Enter() error {
+	if t == nil {
+		return nil
+	}
+	t.depth++
+	if max := t.budget.limits.MaxDepth; max != 0 && t.depth > max {
+		return &Error{Kind: DepthLimit, Limit: int64(max)}
+	}
+	return nil
+}
+
+// Exit records that a call to Eval has finished.
+func (t *Tracker) Exit() {
+	if t == nil {
+		return
+	}
+	t.depth--
+}
+
+// Allocate records that `n` cons cells are about to be allocated, and returns
+// an error if that would take the evaluation over its limit. Builtins which
+// create lists call this before they do, so a list which is too big is never
+// created.
+func Allocate(ctx context.Context, n int) error {
+	tracker := From(ctx)
+	if tracker == nil || tracker.budget.limits.MaxConsCells == 0 {
+		return nil
+	}
+	if atomic.AddInt64(&tracker.budget.consCells, int64(n)) > tracker.budget.limits.MaxConsCells {
+		return &Error{Kind: ConsCellLimit, Limit: tracker.budget.limits.MaxConsCells}
+	}
+	return nil
+}
+
+// Check returns an Error of kind Cancelled if `ctx` has been cancelled.
+func Check(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &Error{Kind: Cancelled, Err: err}
+	}
+	return nil
+}
+
+type parallelismKey struct{}
+
+// WithParallelism returns a copy of `ctx` which lets builtins which call
+// functions in parallel, such as pmap and pfilter, use at most `n` goroutines.
+func WithParallelism(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, parallelismKey{}, n)
+}
+
+// Parallelism returns the number of goroutines builtins which call functions
+// in parallel can use. It defaults to runtime.GOMAXPROCS.
+func Parallelism(ctx context.Context) int {
+	if n, ok := ctx.Value(parallelismKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
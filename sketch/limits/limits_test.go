@@ -0,0 +1,84 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_NoLimits(t *testing.T) {
+	tracker := From(context.Background())
+	assert.Nil(t, tracker)
+	assert.NoError(t, tracker.Step())
+	assert.NoError(t, tracker.Enter())
+	tracker.Exit()
+	assert.NoError(t, Allocate(context.Background(), 1000))
+}
+
+func TestTracker_Steps(t *testing.T) {
+	tracker := From(NewContext(context.Background(), Limits{MaxSteps: 2}))
+	require.NoError(t, tracker.Step())
+	require.NoError(t, tracker.Step())
+
+	err := tracker.Step()
+	var limitErr *Error
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, StepLimit, limitErr.Kind)
+	assert.EqualError(t, err, "evaluation exceeded its limit of 2 steps")
+}
+
+func TestTracker_Depth(t *testing.T) {
+	ctx := NewContext(context.Background(), Limits{MaxDepth: 2})
+	tracker := From(ctx)
+	require.NoError(t, tracker.Enter())
+	require.NoError(t, tracker.Enter())
+	assert.Error(t, tracker.Enter())
+	tracker.Exit()
+	tracker.Exit()
+	assert.NoError(t, tracker.Enter())
+
+	// Forked trackers start at the depth of the tracker they were forked
+	// from, but track their depth separately
+	forked := From(Fork(ctx))
+	assert.Error(t, forked.Enter())
+	forked.Exit()
+	forked.Exit()
+	assert.Equal(t, 1, forked.depth)
+	assert.Equal(t, 2, tracker.depth)
+}
+
+func TestAllocate(t *testing.T) {
+	ctx := NewContext(context.Background(), Limits{MaxConsCells: 10})
+	require.NoError(t, Allocate(ctx, 6))
+	// Forked contexts share the budget
+	require.NoError(t, Allocate(Fork(ctx), 4))
+
+	err := Allocate(ctx, 1)
+	var limitErr *Error
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, ConsCellLimit, limitErr.Kind)
+}
+
+func TestCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	assert.NoError(t, Check(ctx))
+	cancel()
+
+	err := Check(ctx)
+	var limitErr *Error
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, Cancelled, limitErr.Kind)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestParallelism(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, runtime.GOMAXPROCS(0), Parallelism(ctx))
+	assert.Equal(t, 3, Parallelism(WithParallelism(ctx, 3)))
+	// Invalid settings fall back to the default
+	assert.Equal(t, runtime.GOMAXPROCS(0), Parallelism(WithParallelism(ctx, 0)))
+}
@@ -15,10 +15,76 @@ func PrettyPrint(ast types.SketchType) string {
 func prettyPrint(ast types.SketchType, indent int) string {
 	switch ast := ast.(type) {
 	case *types.SketchList:
+		if prefix, form, ok := quoteForm(ast); ok {
+			return prefix + prettyPrint(form, indent)
+		}
 		return prettyPrintList(ast, indent)
+	default:
+		return printSingleLine(ast)
+	}
+}
+
+// quotePrefixes maps the symbols of the quoting forms to the reader macro
+// characters they're written with.
+var quotePrefixes = map[string]string{
+	"quote":          "'",
+	"quasiquote":     "`",
+	"unquote":        "~",
+	"splice-unquote": "~@",
+}
+
+// quoteForm checks whether `list` is a quoting form, such as (quote x). If so,
+// it returns the reader macro character the form is written with, and the
+// quoted form.
+func quoteForm(list *types.SketchList) (string, types.SketchType, bool) {
+	if list.List.Length() != 2 {
+		return "", nil, false
+	}
+	symbol, ok := list.List.First().(*types.SketchSymbol)
+	if !ok {
+		return "", nil, false
+	}
+	prefix, ok := quotePrefixes[symbol.Value]
+	if !ok {
+		return "", nil, false
+	}
+	return prefix, list.List.Rest().First(), true
+}
+
+// printSingleLine returns the form printed on a single line. It's like
+// String, but prints quoting forms using their reader macro characters, at
+// any depth inside lists, vectors, hash maps and sets.
+func printSingleLine(ast types.SketchType) string {
+	var items []types.SketchType
+	var open, close string
+	switch ast := ast.(type) {
+	case *types.SketchList:
+		if prefix, form, ok := quoteForm(ast); ok {
+			return prefix + printSingleLine(form)
+		}
+		items, open, close = ast.List.ToSlice(), "(", ")"
+	case *types.SketchVector:
+		items, open, close = ast.Vector.ToSlice(), "[", "]"
+	case *types.SketchHashMap:
+		for _, key := range ast.Keys() {
+			// Keys come from the map, so Get can't error
+			value, _ := ast.Get(key)
+			items = append(items, key, value)
+		}
+		open, close = "{", "}"
+	case *types.SketchSet:
+		items, open, close = ast.Items(), "#{", "}"
+	case *types.SketchString:
+		return printString(ast.Value)
 	default:
 		return ast.String()
 	}
+
+	itemStrings := make([]string, len(items))
+	for i, item := range items {
+		itemStrings[i] = printSingleLine(item)
+	}
+	return open + strings.Join(itemStrings, " ") + close
 }
 
 // prettyPrintList returns a 'pretty' version of the list. The rules are:
@@ -51,14 +117,14 @@ func prettyPrintList(list *types.SketchList, indent int) string {
 	// comment, because they need a newline after them to not accidentally
 	// comment out too much
 	if !containsComment {
-		trial := list.String()
+		trial := printSingleLine(list)
 		if len(trial)+(indent*2) < 80 {
 			return trial
 		}
 	}
 
 	var b bytes.Buffer
-	fmt.Fprintf(&b, "(%s", items[0])
+	fmt.Fprintf(&b, "(%s", printSingleLine(items[0]))
 
 	args := items[1:]
 	for i, arg := range args {
@@ -83,6 +149,18 @@ func prettyPrintList(list *types.SketchList, indent int) string {
 	return b.String()
 }
 
+// printString returns the string as a string literal. Unlike
+// SketchString.String, newlines are printed as they are, rather than escaped,
+// so multi-line strings such as docstrings keep their formatting.
+func printString(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		quoted := types.QuoteString(line)
+		lines[i] = quoted[1 : len(quoted)-1]
+	}
+	return `"` + strings.Join(lines, "\n") + `"`
+}
+
 // TODO: switch to tabs
 func getIndent(indent int) string {
 	return strings.Repeat("  ", indent)
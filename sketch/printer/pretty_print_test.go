@@ -0,0 +1,112 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch/reader"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyPrint_Strings(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "escape sequences",
+			input:    `(f "a\"b\\c\td")`,
+			expected: `(f "a\"b\\c\td")`,
+		},
+		{
+			name:     "newlines are printed as they are",
+			input:    "(f \"a\\nb\nc\")",
+			expected: "(f \"a\nb\nc\")",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ast, err := reader.ReadWithoutReaderMacros(tc.input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, PrettyPrint(ast))
+		})
+	}
+}
+
+func TestPrettyPrint_Quoting(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "quote",
+			input:    "(quote a)",
+			expected: "'a",
+		},
+		{
+			name:     "quasiquote",
+			input:    "`(a ~b ~@c)",
+			expected: "`(a ~b ~@c)",
+		},
+		{
+			name:     "long forms are printed in short syntax",
+			input:    "(quasiquote (a (unquote b) (splice-unquote c)))",
+			expected: "`(a ~b ~@c)",
+		},
+		{
+			name:     "quote inside a vector",
+			input:    "['a]",
+			expected: "['a]",
+		},
+		{
+			name:     "quote inside a hash map",
+			input:    "{:a 'x 'y [`z]}",
+			expected: "{:a 'x 'y [`z]}",
+		},
+		{
+			name:     "quote inside a set",
+			input:    "#{'x [~y \"z\"]}",
+			expected: "#{'x [~y \"z\"]}",
+		},
+		{
+			name:     "strings inside a hash map",
+			input:    `{"a\tb" "c"}`,
+			expected: `{"a\tb" "c"}`,
+		},
+		{
+			name:     "quote symbol with the wrong number of arguments",
+			input:    "(quote a b)",
+			expected: "(quote a b)",
+		},
+		{
+			name:  "multi-line form",
+			input: "(defmacro m (fn (a) `(def ~a \"a long string which is long enough to make this list wrap onto multiple lines\")))",
+			expected: "(defmacro\n" +
+				"  m\n" +
+				"  (fn\n" +
+				"    (a)\n" +
+				"    `(def\n" +
+				"      ~a\n" +
+				"      \"a long string which is long enough to make this list wrap onto multiple lines\")))",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ast, err := reader.ReadWithoutReaderMacros(tc.input)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, PrettyPrint(ast))
+		})
+	}
+}
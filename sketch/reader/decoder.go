@@ -0,0 +1,115 @@
+package reader
+
+import (
+	"io"
+	"strings"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+)
+
+// Decoder reads successive top level forms from an io.Reader. The input is
+// read as it's needed, so the whole of it doesn't need to be in memory.
+//
+// Comments between top level forms aren't forms, so they aren't returned by
+// Decode. Instead, they're available as trivia, from Trivia.
+type Decoder struct {
+	reader *Reader
+	trivia []*types.SketchComment
+}
+
+// NewDecoder returns a decoder which reads from `r`. The position of each form
+// read records `filename` as the file it came from.
+func NewDecoder(r io.Reader, filename string) *Decoder {
+	return &Decoder{
+		reader: &Reader{
+			source: newScanner(r, filename),
+		},
+	}
+}
+
+// Decode reads the next top level form, strips comments from it and expands
+// reader macros in it. Returns io.EOF once every form has been read.
+//
+// If the form contains syntax errors, the returned error is a ParseErrors,
+// listing each of them. The decoder skips past the form, so Decode can be
+// called again to read the forms after it.
+func (d *Decoder) Decode() (types.SketchType, error) {
+	form, err := d.DecodeRaw()
+	if err != nil {
+		return nil, err
+	}
+	return expandReaderMacros(form), nil
+}
+
+// DecodeRaw is like Decode, but returns the form as it was written, without
+// stripping comments from it or expanding reader macros. It's used to format
+// source code.
+func (d *Decoder) DecodeRaw() (types.SketchType, error) {
+	reader := d.reader
+	d.trivia = nil
+
+	// Drop the tokens of the forms we've already read, so we only keep the
+	// current form in memory
+	reader.Tokens = reader.Tokens[reader.Position:]
+	reader.Position = 0
+
+	for {
+		tok, err := reader.Peek()
+		if err != nil {
+			if reader.source.err != nil {
+				return nil, reader.source.err
+			}
+			return nil, io.EOF
+		}
+		if !strings.HasPrefix(tok.Value, ";") {
+			break
+		}
+		comment, err := ReadAtom(reader)
+		if err != nil {
+			return nil, err
+		}
+		d.trivia = append(d.trivia, comment.(*types.SketchComment))
+	}
+
+	form, err := ReadForm(reader)
+	if reader.source.err != nil {
+		return nil, reader.source.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return form, nil
+}
+
+// Trivia returns the top level comments read before the form most recently
+// returned by Decode or DecodeRaw. Once they've returned io.EOF, it returns
+// the comments at the end of the input.
+func (d *Decoder) Trivia() []*types.SketchComment {
+	return d.trivia
+}
+
+// DecodeAll reads every remaining form, using Decode. If any of them contain
+// syntax errors, the returned error is a ParseErrors listing every error.
+func (d *Decoder) DecodeAll() ([]types.SketchType, error) {
+	var forms []types.SketchType
+	var errs ParseErrors
+	for {
+		form, err := d.Decode()
+		if err == io.EOF {
+			break
+		}
+		if parseErrs, ok := err.(ParseErrors); ok {
+			errs = append(errs, parseErrs...)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		forms = append(forms, form)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return forms, nil
+}
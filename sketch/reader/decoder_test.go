@@ -0,0 +1,102 @@
+package reader
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	input := `; about a
+(def a 1)
+; about f
+(defn f (x) ; inline
+  "a multi-line
+docstring"
+  s.join)
+; trailing`
+	// Read one byte at a time, to check that forms can span reads
+	decoder := NewDecoder(iotest.OneByteReader(strings.NewReader(input)), "test.skt")
+
+	form, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, sList(sSym("def"), sSym("a"), sInt(1)), clearPositions(form))
+	assert.Equal(t, types.Position{File: "test.skt", Line: 2, Column: 1}, types.PositionOf(form))
+	assert.Equal(t, []*types.SketchComment{
+		{Value: "about a", Pos: types.Position{File: "test.skt", Line: 1, Column: 1}},
+	}, decoder.Trivia())
+
+	form, err = decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, sList(
+		sSym("defn"), sSym("f"), sList(sSym("x")),
+		sStr("a multi-line\ndocstring"),
+		sList(sSym("module-lookup"), sSym("s"), sSym("join")),
+	), clearPositions(form))
+	assert.Equal(t, types.Position{File: "test.skt", Line: 4, Column: 1}, types.PositionOf(form))
+	require.Len(t, decoder.Trivia(), 1)
+	assert.Equal(t, "about f", decoder.Trivia()[0].Value)
+
+	_, err = decoder.Decode()
+	assert.Equal(t, io.EOF, err)
+	require.Len(t, decoder.Trivia(), 1)
+	assert.Equal(t, "trailing", decoder.Trivia()[0].Value)
+}
+
+func TestDecoder_DecodeRaw(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("(a ; comment\n s.f 'b)"), "")
+
+	form, err := decoder.DecodeRaw()
+	require.NoError(t, err)
+	assert.Equal(t, sList(
+		sSym("a"), sComment("comment"), sSym("s.f"), sList(sSym("quote"), sSym("b")),
+	), clearPositions(form))
+}
+
+func TestDecoder_ContinuesAfterParseErrors(t *testing.T) {
+	decoder := NewDecoder(strings.NewReader("(a 1/0) ) (b)"), "")
+
+	_, err := decoder.Decode()
+	var parseErrors ParseErrors
+	require.ErrorAs(t, err, &parseErrors)
+	assert.Equal(t, InvalidForm, parseErrors[0].Kind)
+
+	_, err = decoder.Decode()
+	require.ErrorAs(t, err, &parseErrors)
+	assert.Equal(t, UnexpectedDelimiter, parseErrors[0].Kind)
+
+	form, err := decoder.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, sList(sSym("b")), clearPositions(form))
+
+	_, err = decoder.Decode()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestDecoder_DecodeAll(t *testing.T) {
+	forms, err := NewDecoder(strings.NewReader("1 ; one\n2 3"), "").DecodeAll()
+	require.NoError(t, err)
+	require.Len(t, forms, 3)
+	for i, form := range forms {
+		assert.Equal(t, sInt(i+1), clearPositions(form))
+	}
+
+	_, err = NewDecoder(strings.NewReader("(1 ]) (2"), "").DecodeAll()
+	var parseErrors ParseErrors
+	require.ErrorAs(t, err, &parseErrors)
+	assert.Len(t, parseErrors, 2)
+}
+
+func TestDecoder_ReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader("(a b"), iotest.ErrReader(readErr))
+
+	_, err := NewDecoder(r, "").Decode()
+	assert.Equal(t, readErr, err)
+}
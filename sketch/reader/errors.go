@@ -0,0 +1,145 @@
+package reader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+)
+
+// ParseErrorKind describes the type of a syntax error found by the reader.
+type ParseErrorKind int
+
+const (
+	// UnclosedDelimiter is reported when a list, vector, hash map or set isn't
+	// closed before the end of the input, or before the form containing it is
+	// closed
+	UnclosedDelimiter ParseErrorKind = iota
+	// UnexpectedDelimiter is reported for a closing delimiter which doesn't
+	// close anything
+	UnexpectedDelimiter
+	// UnterminatedString is reported when a string isn't closed before the
+	// end of the input
+	UnterminatedString
+	// OddHashMapForms is reported for a hash map literal which contains an
+	// odd number of forms
+	OddHashMapForms
+	// MissingForm is reported when a reader macro such as ' isn't followed by
+	// a form
+	MissingForm
+	// InvalidForm is reported for forms which are malformed, such as numbers
+	// which can't be parsed, strings with invalid escape sequences and
+	// collections with invalid items
+	InvalidForm
+)
+
+func (k ParseErrorKind) String() string {
+	switch k {
+	case UnclosedDelimiter:
+		return "unclosed delimiter"
+	case UnexpectedDelimiter:
+		return "unexpected delimiter"
+	case UnterminatedString:
+		return "unterminated string"
+	case OddHashMapForms:
+		return "odd number of hash map forms"
+	case MissingForm:
+		return "missing form"
+	case InvalidForm:
+		return "invalid form"
+	}
+	return fmt.Sprintf("ParseErrorKind(%d)", int(k))
+}
+
+// ParseError is a syntax error found while reading source code.
+type ParseError struct {
+	Kind ParseErrorKind
+	// Pos is the position the error was found at
+	Pos types.Position
+	// Delimiter is the delimiter the error is about, for errors of kind
+	// UnclosedDelimiter, UnexpectedDelimiter and OddHashMapForms. For unclosed
+	// delimiters and hash maps, it's the opening delimiter
+	Delimiter string
+	// OpenedAt is the position of the opening delimiter, for errors of kind
+	// UnclosedDelimiter and OddHashMapForms
+	OpenedAt types.Position
+	// Message describes the error
+	Message string
+	// Incomplete is set for errors caused by the input ending before the
+	// form being read did, such as a list missing its closing paren. These
+	// errors could be fixed by more input
+	Incomplete bool
+}
+
+func (e *ParseError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+func newParseError(kind ParseErrorKind, pos types.Position, format string, a ...interface{}) *ParseError {
+	return &ParseError{
+		Kind:    kind,
+		Pos:     pos,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+// ParseErrors is the list of every syntax error found while reading some
+// source code, in the order they appear in it.
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the individual errors, so errors.As can be used to find a
+// *ParseError.
+func (e ParseErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// IsIncomplete returns whether `err` is a ParseErrors which only reports that
+// the input ended before the last form being read was complete. The REPL uses
+// this to decide whether to wait for more input.
+func IsIncomplete(err error) bool {
+	parseErrors, ok := err.(ParseErrors)
+	if !ok || len(parseErrors) == 0 {
+		return false
+	}
+	for _, err := range parseErrors {
+		if !err.Incomplete {
+			return false
+		}
+	}
+	return true
+}
+
+// parseErrors returns the syntax errors in `err`, which is returned by one of
+// the Read functions.
+func parseErrors(err error) ParseErrors {
+	switch err := err.(type) {
+	case nil:
+		return nil
+	case ParseErrors:
+		return err
+	case *ParseError:
+		return ParseErrors{err}
+	}
+	return ParseErrors{newParseError(InvalidForm, types.Position{}, "%s", err)}
+}
+
+// lineColumn returns the position in the form 14:3, without the file name.
+// It's used to refer to other positions in the same file in error messages.
+func lineColumn(pos types.Position) string {
+	return fmt.Sprintf("%d:%d", pos.Line, pos.Column)
+}
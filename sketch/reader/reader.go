@@ -3,186 +3,535 @@
 package reader
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/jamesroutley/sketch/sketch/types"
 )
 
+var intRegexp = regexp.MustCompile(`^[+-]?\d+$`)
+
+// ratioRegexp matches ratio literals, such as 1/3 and -22/7
+var ratioRegexp = regexp.MustCompile(`^[+-]?\d+/\d+$`)
+
+// floatRegexp matches float literals, such as 1.5, -.5, 1e10 and 2.5E-3. We
+// check for ints before floats, so this doesn't need to exclude them.
+var floatRegexp = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
 type Reader struct {
-	Tokens   []string
+	Tokens   []*Token
 	Position int
+	// open is the stack of opening delimiters of the collections currently
+	// being read
+	open []*Token
+	// source, if set, is where tokens are read from once we've read all of
+	// Tokens. This lets a Decoder read tokens as they're needed
+	source *scanner
 }
 
-func NewReader(tokens []string) *Reader {
+func NewReader(tokens []*Token) *Reader {
 	return &Reader{
 		Tokens:   tokens,
 		Position: 0,
 	}
 }
 
-func (r *Reader) Peek() (string, error) {
-	if r.Position == len(r.Tokens) {
-		return "", fmt.Errorf("EOF")
+// fill makes sure there's a token at Position, reading one from the source
+// if needed. Returns false if there are no tokens left.
+func (r *Reader) fill() bool {
+	if r.Position < len(r.Tokens) {
+		return true
+	}
+	if r.source == nil {
+		return false
+	}
+	token, ok := r.source.Next()
+	if !ok {
+		return false
+	}
+	r.Tokens = append(r.Tokens, token)
+	return true
+}
+
+func (r *Reader) Peek() (*Token, error) {
+	if !r.fill() {
+		return nil, fmt.Errorf("EOF")
 	}
-	return strings.Trim(r.Tokens[r.Position], " ,\n\t"), nil
+	return r.Tokens[r.Position], nil
 }
 
-func (r *Reader) Next() (string, error) {
-	if r.Position == len(r.Tokens) {
-		return "", fmt.Errorf("EOF")
+func (r *Reader) Next() (*Token, error) {
+	if !r.fill() {
+		return nil, fmt.Errorf("EOF")
 	}
-	current := strings.Trim(r.Tokens[r.Position], " ,\n\t")
+	current := r.Tokens[r.Position]
 	r.Position++
 	return current, nil
 }
 
-func Read(s string) (types.SketchType, error) {
-	ast, err := ReadWithoutReaderMacros(s)
-	if err != nil {
-		return nil, err
+// end returns the position just after the last token.
+func (r *Reader) end() types.Position {
+	if len(r.Tokens) == 0 {
+		return types.Position{}
 	}
+	last := r.Tokens[len(r.Tokens)-1]
+	pos := last.Pos
+	for _, c := range last.Value {
+		if c == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+	}
+	return pos
+}
 
-	if _, ok := ast.(*types.SketchComment); ok {
-		// If the read line is a comment, return this magic error which the
-		// REPL catches to not print anything.
-		return nil, fmt.Errorf("read comment")
-
+// closes returns whether `closer` closes one of the collections currently
+// being read.
+func (r *Reader) closes(closer string) bool {
+	for _, open := range r.open {
+		if closingDelimiters[open.Value] == closer {
+			return true
+		}
 	}
+	return false
+}
 
-	// Reader macros
-	ast = stripComments2(ast)
-	ast = expandModuleLookup(ast)
-	return ast, nil
+// closingDelimiters maps each opening delimiter to the delimiter which closes
+// it
+var closingDelimiters = map[string]string{
+	"(":  ")",
+	"[":  "]",
+	"{":  "}",
+	"#{": "}",
+}
+
+func isClosingDelimiter(token string) bool {
+	return token == ")" || token == "]" || token == "}"
+}
+
+// Read reads the first form from `s`. If `s` contains syntax errors, the
+// returned error is a ParseErrors, listing each of them. If `s` doesn't
+// contain any forms, io.EOF is returned.
+func Read(s string) (types.SketchType, error) {
+	return NewDecoder(strings.NewReader(s), "").Decode()
 }
 
 func ReadWithoutReaderMacros(s string) (types.SketchType, error) {
-	tokens := Tokenize(s)
+	tokens := TokenizeWithPositions("", s)
 	reader := NewReader(tokens)
-	return ReadForm(reader)
+	form, err := ReadForm(reader)
+	if err != nil {
+		return nil, err
+	}
+	return form, nil
 }
 
+func expandReaderMacros(ast types.SketchType) types.SketchType {
+	ast = stripComments2(ast)
+	ast = expandModuleLookup(ast)
+	return ast
+}
+
+// ReadForm reads the next form. If the form contains syntax errors, the
+// returned error is a ParseErrors listing each of them, and the returned form
+// is as much of the form as could be read, or nil.
 func ReadForm(reader *Reader) (types.SketchType, error) {
 	token, err := reader.Peek()
 	if err != nil {
 		return nil, err
 	}
 
-	switch token {
+	if isClosingDelimiter(token.Value) {
+		reader.Next()
+		err := newParseError(UnexpectedDelimiter, token.Pos, "unexpected `%s`", token.Value)
+		err.Delimiter = token.Value
+		return nil, ParseErrors{err}
+	}
+
+	switch token.Value {
 	case "(":
-		// Increment the position pointer
-		_, err = reader.Next()
-		if err != nil {
-			return nil, err
-		}
 		return ReadList(reader)
+	case "[":
+		return ReadVector(reader)
 	case "{":
-		// Increment the position pointer
-		_, err = reader.Next()
-		if err != nil {
-			return nil, err
-		}
 		return ReadHashMap(reader)
+	case "#{":
+		return ReadSet(reader)
+	case "'", "`", "~", "~@":
+		return ReadQuote(reader)
 	default:
 		return ReadAtom(reader)
 	}
 }
 
-func ReadList(reader *Reader) (types.SketchType, error) {
+// quoteSymbols maps each of the quoting reader macro characters to the
+// symbol of the form it expands to.
+var quoteSymbols = map[string]string{
+	"'":  "quote",
+	"`":  "quasiquote",
+	"~":  "unquote",
+	"~@": "splice-unquote",
+}
+
+// ReadQuote reads a form prefixed with one of the quoting reader macros, and
+// expands it into the equivalent list. 'x is read as (quote x), `x as
+// (quasiquote x), ~x as (unquote x) and ~@x as (splice-unquote x).
+func ReadQuote(reader *Reader) (types.SketchType, error) {
+	// Consume the macro character. Its position is the position of the list
+	macro, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	// We don't consume a closing delimiter here, so it can close the
+	// collection the macro character is in
+	if tok, err := reader.Peek(); err != nil || isClosingDelimiter(tok.Value) {
+		missing := newParseError(MissingForm, macro.Pos, "expected a form after %s", macro.Value)
+		missing.Incomplete = err != nil
+		return nil, ParseErrors{missing}
+	}
+	form, err := ReadForm(reader)
+	if form == nil {
+		return nil, err
+	}
+
+	return &types.SketchList{
+		List: types.NewList([]types.SketchType{
+			&types.SketchSymbol{Value: quoteSymbols[macro.Value], Pos: macro.Pos},
+			form,
+		}),
+		Pos: macro.Pos,
+	}, err
+}
+
+// readItems reads the items of a collection, up to and including its closing
+// delimiter. `open` is the collection's opening delimiter, which has already
+// been consumed. Syntax errors in the items are collected, so that every
+// error in the collection is reported.
+func readItems(reader *Reader, open *Token) ([]types.SketchType, ParseErrors) {
+	closer := closingDelimiters[open.Value]
+	reader.open = append(reader.open, open)
+	defer func() {
+		reader.open = reader.open[:len(reader.open)-1]
+	}()
+
 	var items []types.SketchType
+	var errs ParseErrors
 	for {
-		// TODO: error case when we hit file without closing bracket
 		tok, err := reader.Peek()
 		if err != nil {
-			return nil, err
+			unclosed := unclosedError(open, reader.end())
+			unclosed.Incomplete = true
+			return items, append(errs, unclosed)
 		}
-		if tok == ")" {
+		if tok.Value == closer {
 			// Increment the position pointer
-			_, err := reader.Next()
-			if err != nil {
-				return nil, err
-			}
-			return &types.SketchList{
-				List: types.NewList(items),
-			}, nil
+			reader.Next()
+			return items, errs
 		}
+		if isClosingDelimiter(tok.Value) && reader.closes(tok.Value) {
+			// The delimiter closes a collection this one is inside, so this
+			// one must be missing its closing delimiter. We leave the
+			// delimiter for the enclosing collection to consume
+			return items, append(errs, unclosedError(open, tok.Pos))
+		}
+
 		item, err := ReadForm(reader)
-		if err != nil {
-			return nil, err
+		errs = append(errs, parseErrors(err)...)
+		if item != nil {
+			items = append(items, item)
 		}
-		items = append(items, item)
 	}
 }
 
+func unclosedError(open *Token, pos types.Position) *ParseError {
+	err := newParseError(UnclosedDelimiter, pos, "unclosed `%s` opened at %s", open.Value, lineColumn(open.Pos))
+	err.Delimiter = open.Value
+	err.OpenedAt = open.Pos
+	return err
+}
+
+// errorOrNil returns `errs`, or nil if there are no errors. This avoids
+// returning a non-nil error interface holding an empty ParseErrors.
+func errorOrNil(errs ParseErrors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func ReadList(reader *Reader) (types.SketchType, error) {
+	// Consume the opening paren. Its position is the position of the list
+	open, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	items, errs := readItems(reader, open)
+	return &types.SketchList{
+		List: types.NewList(items),
+		Pos:  open.Pos,
+	}, errorOrNil(errs)
+}
+
+func ReadVector(reader *Reader) (types.SketchType, error) {
+	// Consume the opening bracket. Its position is the position of the vector
+	open, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	items, errs := readItems(reader, open)
+	return &types.SketchVector{
+		Vector: types.NewVector(items),
+		Pos:    open.Pos,
+	}, errorOrNil(errs)
+}
+
+func ReadSet(reader *Reader) (types.SketchType, error) {
+	// Consume the opening #{. Its position is the position of the set
+	open, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	items, errs := readItems(reader, open)
+	set, err := types.NewSketchSet(items)
+	if err != nil {
+		return nil, append(errs, newParseError(InvalidForm, open.Pos, "%s", err))
+	}
+	set.Pos = open.Pos
+	return set, errorOrNil(errs)
+}
+
 func ReadHashMap(reader *Reader) (types.SketchType, error) {
-	var items []types.SketchType
-	for {
-		// TODO: error case when we hit file without closing bracket
-		tok, err := reader.Peek()
-		if err != nil {
-			return nil, err
-		}
-		if tok == "}" {
-			// Increment the position pointer
-			_, err := reader.Next()
-			if err != nil {
-				return nil, err
-			}
-			return types.NewSketchHashMap(items)
-		}
-		item, err := ReadForm(reader)
-		if err != nil {
-			return nil, err
-		}
-		items = append(items, item)
+	// Consume the opening brace. Its position is the position of the hashmap
+	open, err := reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	items, errs := readItems(reader, open)
+	if len(items)%2 != 0 {
+		err := newParseError(
+			OddHashMapForms, open.Pos,
+			"hash map literal must contain an even number of forms, got %d", len(items),
+		)
+		err.Delimiter = open.Value
+		err.OpenedAt = open.Pos
+		return nil, append(errs, err)
+	}
+	hashMap, err := types.NewSketchHashMap(items)
+	if err != nil {
+		return nil, append(errs, newParseError(InvalidForm, open.Pos, "%s", err))
 	}
+	hashMap.Pos = open.Pos
+	return hashMap, errorOrNil(errs)
 }
 
 func ReadAtom(reader *Reader) (types.SketchType, error) {
-	token, err := reader.Next()
+	tok, err := reader.Next()
 	if err != nil {
 		return nil, err
 	}
+	token, pos := tok.Value, tok.Pos
 
 	if strings.HasPrefix(token, ";") {
 		comment := strings.TrimLeft(token, "; ")
 		return &types.SketchComment{
 			Value: comment,
+			Pos:   pos,
 		}, nil
 	}
 
 	if num, err := strconv.Atoi(token); err == nil {
 		return &types.SketchInt{
 			Value: num,
+			Pos:   pos,
+		}, nil
+	}
+
+	// Ints which are too large to fit in an int are read as big ints
+	if intRegexp.MatchString(token) {
+		num, ok := new(big.Int).SetString(token, 10)
+		if !ok {
+			return nil, ParseErrors{newParseError(InvalidForm, pos, "invalid int %s", token)}
+		}
+		return &types.SketchBigInt{
+			Value: num,
+			Pos:   pos,
 		}, nil
 	}
 
+	if ratioRegexp.MatchString(token) {
+		return readRatio(token, pos)
+	}
+
+	if floatRegexp.MatchString(token) {
+		num, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, ParseErrors{newParseError(InvalidForm, pos, "invalid float %s: %s", token, err)}
+		}
+		return &types.SketchFloat{
+			Value: num,
+			Pos:   pos,
+		}, nil
+	}
+
+	// Keywords start with a colon
+	if len(token) > 1 && token[0] == ':' {
+		keyword := types.NewKeyword(token[1:])
+		keyword.Pos = pos
+		return keyword, nil
+	}
+
 	if token == "true" {
-		return &types.SketchBoolean{Value: true}, nil
+		return &types.SketchBoolean{Value: true, Pos: pos}, nil
 	}
 	if token == "false" {
-		return &types.SketchBoolean{Value: false}, nil
+		return &types.SketchBoolean{Value: false, Pos: pos}, nil
 	}
 
 	if token == "nil" {
-		return &types.SketchNil{}, nil
+		return &types.SketchNil{Pos: pos}, nil
 	}
 
 	if strings.HasPrefix(token, `"`) {
-		if !strings.HasSuffix(token, `"`) {
-			return nil, fmt.Errorf("unclosed string")
+		value, err := unquoteString(token)
+		if err == errUnterminatedString {
+			unterminated := newParseError(UnterminatedString, pos, "unterminated string")
+			unterminated.Incomplete = true
+			return nil, ParseErrors{unterminated}
+		}
+		if err != nil {
+			return nil, ParseErrors{newParseError(InvalidForm, pos, "%s", err)}
 		}
-		token = strings.Trim(token, `"`)
-		// Replace the literal characters 'slash n' with a newline
-		token = strings.Replace(token, `\n`, "\n", -1)
-
 		return &types.SketchString{
-			Value: strings.Trim(token, `"`),
+			Value: value,
+			Pos:   pos,
 		}, nil
 	}
 
 	return &types.SketchSymbol{
 		Value: token,
+		Pos:   pos,
 	}, nil
 }
+
+// unquoteString returns the value of the string literal `token`, replacing
+// escape sequences with the characters they represent. The escape sequences
+// supported by Go and JSON strings are understood, including JSON's UTF-16
+// surrogate pairs, such as \ud83d\ude00.
+func unquoteString(token string) (string, error) {
+	var b strings.Builder
+	s := token[1:]
+	for len(s) > 0 {
+		switch s[0] {
+		case '"':
+			if len(s) > 1 {
+				return "", fmt.Errorf("unexpected characters after string: %s", s[1:])
+			}
+			return b.String(), nil
+		case '\\':
+			rest, err := unescape(&b, s)
+			if err != nil {
+				return "", err
+			}
+			s = rest
+		default:
+			b.WriteByte(s[0])
+			s = s[1:]
+		}
+	}
+	return "", errUnterminatedString
+}
+
+var errUnterminatedString = errors.New("unterminated string")
+
+// unescape writes the character represented by the escape sequence at the
+// start of `s` to `b`, and returns the rest of `s`.
+func unescape(b *strings.Builder, s string) (string, error) {
+	if strings.HasPrefix(s, `\/`) {
+		// JSON allows forward slashes to be escaped, Go doesn't
+		b.WriteByte('/')
+		return s[2:], nil
+	}
+
+	if high, ok := readUTF16Escape(s); ok && utf16.IsSurrogate(high) {
+		// JSON encodes characters outside the Basic Multilingual Plane as a
+		// pair of UTF-16 surrogates. Go doesn't allow surrogates in \u
+		// escapes, so we decode these ourselves
+		low, ok := readUTF16Escape(s[6:])
+		r := utf16.DecodeRune(high, low)
+		if !ok || r == utf8.RuneError {
+			return "", fmt.Errorf("invalid surrogate pair in string: %s", escapeSequence(s, 12))
+		}
+		b.WriteRune(r)
+		return s[12:], nil
+	}
+
+	value, multibyte, rest, err := strconv.UnquoteChar(s, '"')
+	if err != nil {
+		return "", fmt.Errorf("invalid escape sequence in string: %s", escapeSequence(s, 2))
+	}
+	if multibyte {
+		b.WriteRune(value)
+	} else {
+		// \xHH and octal escapes represent a single byte
+		b.WriteByte(byte(value))
+	}
+	return rest, nil
+}
+
+// readUTF16Escape reads a \uHHHH escape sequence from the start of `s`,
+// returning the UTF-16 code unit it represents.
+func readUTF16Escape(s string) (rune, bool) {
+	if len(s) < 6 || !strings.HasPrefix(s, `\u`) {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(s[2:6], 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	return rune(value), true
+}
+
+// escapeSequence returns the first n bytes of `s`, for use in error messages
+// about the escape sequence it starts with.
+func escapeSequence(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// readRatio reads a ratio literal. Ratios which are integers, such as 4/2, are
+// read as ints.
+func readRatio(token string, pos types.Position) (types.SketchType, error) {
+	numerator, denominator, _ := strings.Cut(token, "/")
+	num, ok := new(big.Int).SetString(numerator, 10)
+	if !ok {
+		return nil, ParseErrors{newParseError(InvalidForm, pos, "invalid ratio %s", token)}
+	}
+	denom, ok := new(big.Int).SetString(denominator, 10)
+	if !ok || denom.Sign() == 0 {
+		return nil, ParseErrors{newParseError(InvalidForm, pos, "invalid ratio %s", token)}
+	}
+	ratio := new(big.Rat).SetFrac(num, denom)
+
+	if !ratio.IsInt() {
+		return &types.SketchRatio{Value: ratio, Pos: pos}, nil
+	}
+	if i := ratio.Num(); i.IsInt64() && int64(int(i.Int64())) == i.Int64() {
+		return &types.SketchInt{Value: int(i.Int64()), Pos: pos}, nil
+	}
+	return &types.SketchBigInt{Value: ratio.Num(), Pos: pos}, nil
+}
@@ -7,16 +7,22 @@ import (
 )
 
 func stripComments2(ast types.SketchType) types.SketchType {
-	list, ok := ast.(*types.SketchList)
-	if !ok {
+	var items []types.SketchType
+	switch ast := ast.(type) {
+	case *types.SketchList:
+		items = ast.List.ToSlice()
+	case *types.SketchVector:
+		items = ast.Vector.ToSlice()
+	default:
 		return ast
 	}
+
 	var newItems []types.SketchType
-	for _, item := range list.List.ToSlice() {
+	for _, item := range items {
 		switch item := item.(type) {
 		case *types.SketchComment:
 			// skip
-		case *types.SketchList:
+		case *types.SketchList, *types.SketchVector:
 			newItem := stripComments2(item)
 			newItems = append(newItems, newItem)
 		default:
@@ -24,8 +30,15 @@ func stripComments2(ast types.SketchType) types.SketchType {
 		}
 	}
 
+	if vector, ok := ast.(*types.SketchVector); ok {
+		return &types.SketchVector{
+			Vector: types.NewVector(newItems),
+			Pos:    vector.Pos,
+		}
+	}
 	return &types.SketchList{
 		List: types.NewList(newItems),
+		Pos:  types.PositionOf(ast),
 	}
 }
 
@@ -39,10 +52,11 @@ func expandModuleLookup(ast types.SketchType) types.SketchType {
 			parts := strings.SplitN(symbol, ".", 2)
 			return &types.SketchList{
 				List: types.NewList([]types.SketchType{
-					&types.SketchSymbol{Value: "module-lookup"},
-					&types.SketchSymbol{Value: parts[0]},
-					&types.SketchSymbol{Value: parts[1]},
+					&types.SketchSymbol{Value: "module-lookup", Pos: ast.Pos},
+					&types.SketchSymbol{Value: parts[0], Pos: ast.Pos},
+					&types.SketchSymbol{Value: parts[1], Pos: ast.Pos},
 				}),
+				Pos: ast.Pos,
 			}
 		}
 	case *types.SketchList:
@@ -54,12 +68,22 @@ func expandModuleLookup(ast types.SketchType) types.SketchType {
 		}
 		return &types.SketchList{
 			List: types.NewList(newItems),
+			Pos:  ast.Pos,
 		}
 
 		// for i, item := range ast.Items {
 		// 	ast.Items[i] = expandModuleLookup(item)
 		// }
 		// return ast
+	case *types.SketchVector:
+		var newItems []types.SketchType
+		for _, item := range ast.Vector.ToSlice() {
+			newItems = append(newItems, expandModuleLookup(item))
+		}
+		return &types.SketchVector{
+			Vector: types.NewVector(newItems),
+			Pos:    ast.Pos,
+		}
 	}
 	return ast
 }
@@ -1,8 +1,11 @@
 package reader
 
 import (
+	"math/big"
+	"strings"
 	"testing"
 
+	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -42,11 +45,45 @@ func TestRead_Strings(t *testing.T) {
 			input:    `"\n"`,
 			expected: sStr("\n"),
 		},
+		{
+			name:     "escaped quotes and backslashes",
+			input:    `"a \"b\" \\n"`,
+			expected: sStr(`a "b" \n`),
+		},
+		{
+			name:     "Go escapes",
+			input:    `"\t\r\a\v\x41\101\u00e9\U0001F600"`,
+			expected: sStr("\t\r\a\vAAé😀"),
+		},
+		{
+			name:     "JSON escapes",
+			input:    `"\/\b\f\ud83d\ude00"`,
+			expected: sStr("/\b\f😀"),
+		},
+		{
+			name:     "invalid UTF-8",
+			input:    `"\xff"`,
+			expected: sStr("\xff"),
+		},
 	}
 
 	runTests(t, cases)
 }
 
+func TestRead_StringErrors(t *testing.T) {
+	for _, input := range []string{
+		`"abc`,
+		`"abc\"`,
+		`"\q"`,
+		`"\u12"`,
+		`"\ud83d"`,
+		`"\ude00\ud83d"`,
+	} {
+		_, err := Read(input)
+		assert.Error(t, err, input)
+	}
+}
+
 func TestRead_Comments(t *testing.T) {
 	cases := []*TestCase{
 		{
@@ -61,6 +98,53 @@ func TestRead_Comments(t *testing.T) {
 	runTests(t, cases)
 }
 
+func TestRead_Vector(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "empty vector",
+			input:    "[]",
+			expected: sVector(),
+		},
+		{
+			name:     "vector",
+			input:    "[1 a (b)]",
+			expected: sVector(sInt(1), sSym("a"), sList(sSym("b"))),
+		},
+		{
+			name:     "nested vector",
+			input:    "[[1] ; comment\n [2]]",
+			expected: sVector(sVector(sInt(1)), sVector(sInt(2))),
+		},
+		{
+			name:     "vector in list",
+			input:    "(fn [a b] s.f)",
+			expected: sList(sSym("fn"), sVector(sSym("a"), sSym("b")), sList(sSym("module-lookup"), sSym("s"), sSym("f"))),
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestRead_Set(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "empty set",
+			input:    "#{}",
+			expected: sSet(),
+		},
+		{
+			name:     "set",
+			input:    "#{1 a (b) 1}",
+			expected: sSet(sInt(1), sSym("a"), sList(sSym("b"))),
+		},
+		{
+			name:     "set in hash map",
+			input:    "{#{1} {}}",
+			expected: sHashMap(sSet(sInt(1)), sHashMap()),
+		},
+	}
+	runTests(t, cases)
+}
+
 func TestRead_HashMap(t *testing.T) {
 	cases := []*TestCase{
 		{
@@ -94,6 +178,100 @@ func TestRead_List(t *testing.T) {
 	runTests(t, cases)
 }
 
+func TestRead_Keyword(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "keyword",
+			input:    "(:a :b-c :)",
+			expected: sList(types.NewKeyword("a"), types.NewKeyword("b-c"), sSym(":")),
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestRead_Quoting(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "quote",
+			input:    "'a",
+			expected: sList(sSym("quote"), sSym("a")),
+		},
+		{
+			name:  "quasiquote, unquote and splice-unquote",
+			input: "`(a ~b ~@(c))",
+			expected: sList(sSym("quasiquote"), sList(
+				sSym("a"),
+				sList(sSym("unquote"), sSym("b")),
+				sList(sSym("splice-unquote"), sList(sSym("c"))),
+			)),
+		},
+		{
+			name:     "nested quotes",
+			input:    "(f ''[a])",
+			expected: sList(sSym("f"), sList(sSym("quote"), sList(sSym("quote"), sVector(sSym("a"))))),
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestRead_QuotingErrors(t *testing.T) {
+	for _, input := range []string{"'", "(a `)"} {
+		_, err := Read(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestRead_Numbers(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "int",
+			input:    "-12",
+			expected: sInt(-12),
+		},
+		{
+			name:     "int too large for a Go int",
+			input:    "123456789012345678901234567890",
+			expected: sBigInt("123456789012345678901234567890"),
+		},
+		{
+			name:     "ratio",
+			input:    "-2/6",
+			expected: &types.SketchRatio{Value: big.NewRat(-1, 3)},
+		},
+		{
+			name:     "integral ratio",
+			input:    "6/2",
+			expected: sInt(3),
+		},
+		{
+			name:     "float",
+			input:    "1.5",
+			expected: sFloat(1.5),
+		},
+		{
+			name:     "float without leading digit",
+			input:    "-.5",
+			expected: sFloat(-0.5),
+		},
+		{
+			name:     "float with exponent",
+			input:    "1e3",
+			expected: sFloat(1000),
+		},
+		{
+			name:     "float with signed exponent",
+			input:    "2.5E-1",
+			expected: sFloat(0.25),
+		},
+		{
+			name:     "symbols which look a bit like numbers",
+			input:    "(1a inf e3 - / 1/ /2)",
+			expected: sList(sSym("1a"), sSym("inf"), sSym("e3"), sSym("-"), sSym("/"), sSym("1/"), sSym("/2")),
+		},
+	}
+	runTests(t, cases)
+}
+
 func TestReadWithoutReaderMacros(t *testing.T) {
 	cases := []*TestCase{
 		{
@@ -118,7 +296,110 @@ func TestReadWithoutReaderMacros(t *testing.T) {
 			actual, err := ReadWithoutReaderMacros(tc.input)
 			require.NoError(t, err)
 
-			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expected, clearPositions(actual))
+		})
+	}
+}
+
+// readProgram reads every form in `s`, recording test.skt as the file they
+// came from
+func readProgram(s string) ([]types.SketchType, error) {
+	return NewDecoder(strings.NewReader(s), "test.skt").DecodeAll()
+}
+
+func TestRead_Positions(t *testing.T) {
+	forms, err := readProgram(`(def a 1)
+
+(defn f (x)
+  ; comment
+  (+ x "é" y))`)
+	require.NoError(t, err)
+	require.Len(t, forms, 2)
+
+	def := forms[0].(*types.SketchList)
+	assert.Equal(t, "test.skt:1:1", def.Pos.String())
+	assert.Equal(t, "test.skt:1:8", types.PositionOf(def.List.ToSlice()[2]).String())
+
+	defn := forms[1].(*types.SketchList).List.ToSlice()
+	assert.Equal(t, "test.skt:3:7", types.PositionOf(defn[1]).String())
+	body := defn[3].(*types.SketchList).List.ToSlice()
+	assert.Equal(t, "test.skt:5:6", types.PositionOf(body[1]).String())
+	// Columns count characters, not bytes
+	assert.Equal(t, "test.skt:5:12", types.PositionOf(body[3]).String())
+}
+
+func TestDecoder_ParseErrors(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "unclosed list",
+			input:    "(def a 1)\n\n(defn f (x)\n  (+ x 1)",
+			expected: []string{"test.skt:4:10: unclosed `(` opened at 3:1"},
+		},
+		{
+			name:     "stray closer",
+			input:    "(def a 1))\n]",
+			expected: []string{"test.skt:1:10: unexpected `)`", "test.skt:2:1: unexpected `]`"},
+		},
+		{
+			name:     "mismatched closer",
+			input:    "(let [a 1) a)",
+			expected: []string{"test.skt:1:10: unclosed `[` opened at 1:6", "test.skt:1:13: unexpected `)`"},
+		},
+		{
+			name:     "unterminated string",
+			input:    "(def a \"abc)\n(def b 2)",
+			expected: []string{"test.skt:1:8: unterminated string", "test.skt:2:10: unclosed `(` opened at 1:1"},
+		},
+		{
+			name:     "odd hash map",
+			input:    "(def a {:a 1 :b})",
+			expected: []string{"test.skt:1:8: hash map literal must contain an even number of forms, got 3"},
+		},
+		{
+			name:  "every error is reported",
+			input: "(def a 1/0)\n(def b \"\\q\")\n(def c ')\n#{1 1",
+			expected: []string{
+				"test.skt:1:8: invalid ratio 1/0",
+				"test.skt:2:8: invalid escape sequence in string: \\q",
+				"test.skt:3:8: expected a form after '",
+				"test.skt:4:6: unclosed `#{` opened at 4:1",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := readProgram(tc.input)
+			require.Error(t, err)
+
+			var parseErrors ParseErrors
+			require.ErrorAs(t, err, &parseErrors)
+			messages := make([]string, len(parseErrors))
+			for i, parseError := range parseErrors {
+				messages[i] = parseError.Error()
+			}
+			assert.Equal(t, tc.expected, messages)
 		})
 	}
 }
+
+func TestDecoder_ParseErrorFields(t *testing.T) {
+	_, err := readProgram("(a\n  [b)")
+
+	var parseError *ParseError
+	require.ErrorAs(t, err, &parseError)
+	assert.Equal(t, &ParseError{
+		Kind:      UnclosedDelimiter,
+		Pos:       types.Position{File: "test.skt", Line: 2, Column: 5},
+		Delimiter: "[",
+		OpenedAt:  types.Position{File: "test.skt", Line: 2, Column: 3},
+		Message:   "unclosed `[` opened at 2:3",
+	}, parseError)
+}
@@ -1,6 +1,8 @@
 package reader
 
 import (
+	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/jamesroutley/sketch/sketch/types"
@@ -37,6 +39,30 @@ func sInt(val int) *types.SketchInt {
 	return &types.SketchInt{Value: val}
 }
 
+func sBigInt(val string) *types.SketchBigInt {
+	i, ok := new(big.Int).SetString(val, 10)
+	if !ok {
+		panic(fmt.Sprintf("invalid big int %s", val))
+	}
+	return &types.SketchBigInt{Value: i}
+}
+
+func sFloat(val float64) *types.SketchFloat {
+	return &types.SketchFloat{Value: val}
+}
+
+func sVector(vals ...types.SketchType) *types.SketchVector {
+	return &types.SketchVector{Vector: types.NewVector(vals)}
+}
+
+func sSet(vals ...types.SketchType) *types.SketchSet {
+	s, err := types.NewSketchSet(vals)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
 func sHashMap(vals ...types.SketchType) *types.SketchHashMap {
 	m, err := types.NewSketchHashMap(vals)
 	if err != nil {
@@ -59,7 +85,64 @@ func runTests(t *testing.T, cases []*TestCase) {
 			actual, err := Read(tc.input)
 			require.NoError(t, err)
 
-			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expected, clearPositions(actual))
 		})
 	}
 }
+
+// clearPositions returns a copy of `ast` with the source positions recorded
+// by the reader removed. This lets us compare the reader's output with hand
+// written ASTs. Positions are tested separately.
+func clearPositions(ast types.SketchType) types.SketchType {
+	switch ast := ast.(type) {
+	case *types.SketchList:
+		items := ast.List.ToSlice()
+		for i, item := range items {
+			items[i] = clearPositions(item)
+		}
+		return sList(items...)
+	case *types.SketchVector:
+		items := ast.Vector.ToSlice()
+		for i, item := range items {
+			items[i] = clearPositions(item)
+		}
+		return sVector(items...)
+	case *types.SketchSet:
+		items := ast.Items()
+		for i, item := range items {
+			items[i] = clearPositions(item)
+		}
+		return sSet(items...)
+	case *types.SketchHashMap:
+		var items []types.SketchType
+		for _, key := range ast.Keys() {
+			value, err := ast.Get(key)
+			if err != nil {
+				panic(err)
+			}
+			items = append(items, clearPositions(key), clearPositions(value))
+		}
+		return sHashMap(items...)
+	case *types.SketchSymbol:
+		return sSym(ast.Value)
+	case *types.SketchKeyword:
+		return types.NewKeyword(ast.Name())
+	case *types.SketchString:
+		return sStr(ast.Value)
+	case *types.SketchComment:
+		return sComment(ast.Value)
+	case *types.SketchInt:
+		return sInt(ast.Value)
+	case *types.SketchBigInt:
+		return &types.SketchBigInt{Value: ast.Value}
+	case *types.SketchRatio:
+		return &types.SketchRatio{Value: ast.Value}
+	case *types.SketchFloat:
+		return sFloat(ast.Value)
+	case *types.SketchBoolean:
+		return &types.SketchBoolean{Value: ast.Value}
+	case *types.SketchNil:
+		return &types.SketchNil{}
+	}
+	return ast
+}
@@ -1,28 +1,173 @@
 package reader
 
 import (
-	"regexp"
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jamesroutley/sketch/sketch/types"
 )
 
+// Token is a single token read from source code, along with the position in
+// the source code it was read from.
 type Token struct {
 	Value string
-	// TODO: these can't be negative - could make uint
-	Line int
-	Row  int
-	// TODO: this would store the character number - is this useful?
-	char int
+	Pos   types.Position
+}
+
+// TokenizeWithPositions splits `s` into tokens, recording the file, line and
+// column each token starts at. Whitespace and commas between tokens are
+// dropped.
+func TokenizeWithPositions(filename string, s string) []*Token {
+	var tokens []*Token
+	scanner := newScanner(strings.NewReader(s), filename)
+	for {
+		token, ok := scanner.Next()
+		if !ok {
+			return tokens
+		}
+		tokens = append(tokens, token)
+	}
+}
+
+// scanner reads tokens one at a time from an io.Reader, keeping track of the
+// line and column of each.
+type scanner struct {
+	r        *bufio.Reader
+	filename string
+	// line and column are the position of the next rune to be read
+	line, column int
+	// err is the error which stopped us reading from r, if it wasn't io.EOF
+	err error
+}
+
+func newScanner(r io.Reader, filename string) *scanner {
+	return &scanner{
+		r:        bufio.NewReader(r),
+		filename: filename,
+		line:     1,
+		column:   1,
+	}
+}
+
+// peek returns the next rune, without consuming it, along with the bytes
+// which encode it. Bytes which aren't valid UTF-8 are returned as one
+// utf8.RuneError each.
+func (s *scanner) peek() (rune, []byte, bool) {
+	b, err := s.r.Peek(utf8.UTFMax)
+	if len(b) == 0 {
+		if err != nil && err != io.EOF {
+			s.err = err
+		}
+		return 0, nil, false
+	}
+	r, size := utf8.DecodeRune(b)
+	return r, b[:size], true
+}
+
+// advance consumes the next rune, writing its bytes to `b`. Returns false at
+// the end of the input.
+func (s *scanner) advance(b *strings.Builder) (rune, bool) {
+	r, bytes, ok := s.peek()
+	if !ok {
+		return 0, false
+	}
+	b.Write(bytes)
+	s.r.Discard(len(bytes))
+	if r == '\n' {
+		s.line++
+		s.column = 1
+	} else {
+		s.column++
+	}
+	return r, true
+}
+
+func isWhitespace(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\f', '\r', ',':
+		return true
+	}
+	return false
+}
+
+// isAtomRune returns whether `r` can be part of an atom, such as a symbol or
+// a number.
+func isAtomRune(r rune) bool {
+	return !isWhitespace(r) && !strings.ContainsRune("[]{}()'\"`;", r)
 }
 
-func Tokenize(s string) []string {
-	re := regexp.MustCompile(`[\s,]*(~@|[\[\]{}()'` + "`" + `~^@]|"(?:\\.|[^\\"])*"?|;.*|[^\s\[\]{}('"` + "`" + `,;)]*)`)
-	return re.FindAllString(s, -1)
+// Next returns the next token. Returns false at the end of the input.
+func (s *scanner) Next() (*Token, bool) {
+	var discarded strings.Builder
+	for {
+		r, _, ok := s.peek()
+		if !ok {
+			return nil, false
+		}
+		if !isWhitespace(r) {
+			break
+		}
+		s.advance(&discarded)
+	}
+
+	pos := types.Position{
+		File:   s.filename,
+		Line:   s.line,
+		Column: s.column,
+	}
+	var b strings.Builder
+	r, _ := s.advance(&b)
+
+	switch {
+	case r == '~':
+		if next, _, ok := s.peek(); ok && next == '@' {
+			s.advance(&b)
+		}
+	case r == '#':
+		if next, _, ok := s.peek(); ok && next == '{' {
+			s.advance(&b)
+		} else {
+			s.readWhile(&b, isAtomRune)
+		}
+	case strings.ContainsRune("[]{}()'`^@", r):
+	case r == '"':
+		s.readString(&b)
+	case r == ';':
+		s.readWhile(&b, func(r rune) bool { return r != '\n' })
+	default:
+		s.readWhile(&b, isAtomRune)
+	}
+
+	return &Token{
+		Value: strings.TrimRight(b.String(), " ,\n\t\r"),
+		Pos:   pos,
+	}, true
 }
 
-// func TokenizeNew(s string) []*Token {
-// 	// Iterate over the string line by line. Sketch doesn't have significant
-// 	// whitespace, but comments comment out the rest of the line
-// 	lines := strings.Split(s, "\n")
-// 	for _, line := range lines {
+// readWhile consumes runes while `f` returns true for them
+func (s *scanner) readWhile(b *strings.Builder, f func(rune) bool) {
+	for {
+		r, _, ok := s.peek()
+		if !ok || !f(r) {
+			return
+		}
+		s.advance(b)
+	}
+}
 
-// 	}
-// }
+// readString consumes the rest of a string, up to and including its closing
+// quote. The opening quote has already been consumed. If the string is
+// unterminated, the rest of the input is consumed.
+func (s *scanner) readString(b *strings.Builder) {
+	for {
+		r, ok := s.advance(b)
+		switch {
+		case !ok, r == '"':
+			return
+		case r == '\\':
+			s.advance(b)
+		}
+	}
+}
@@ -1,11 +1,21 @@
 package reader
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// tokenValues returns the value of each token in `s`
+func tokenValues(s string) []string {
+	var values []string
+	for _, token := range TokenizeWithPositions("", s) {
+		values = append(values, token.Value)
+	}
+	return values
+}
+
 func TestTokenize_Comments(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -21,21 +31,18 @@ func TestTokenize_Comments(t *testing.T) {
 			name: "one line commented, next not",
 			input: `; (+ 1 1)
 1`,
-			// TODO: as this expected case demonstrates - it's a bit strange
-			// that the tokenizer doesn't strip whitespace from the tokens
-			expected: []string{"; (+ 1 1)", "\n1"},
+			expected: []string{"; (+ 1 1)", "1"},
 		},
 		{
 			name:     "comment in middle of line",
 			input:    `1 ; 2`,
-			expected: []string{"1", " ; 2"},
+			expected: []string{"1", "; 2"},
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := Tokenize(tc.input)
-			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expected, tokenValues(tc.input))
 		})
 	}
 }
@@ -49,19 +56,51 @@ func TestTokenize(t *testing.T) {
 		{
 			name:     "list with single character items",
 			input:    "(+ 1 1)",
-			expected: []string{"(", "+", " 1", " 1", ")"},
+			expected: []string{"(", "+", "1", "1", ")"},
 		},
 		{
 			name:     "list with multiple character items",
 			input:    "(add one two)",
-			expected: []string{"(", "add", " one", " two", ")"},
+			expected: []string{"(", "add", "one", "two", ")"},
+		},
+		{
+			name:     "set",
+			input:    "#{1 [2]}",
+			expected: []string{"#{", "1", "[", "2", "]", "}"},
+		},
+		{
+			name:     "commas are whitespace",
+			input:    "[1, 2,3]",
+			expected: []string{"[", "1", "2", "3", "]"},
+		},
+		{
+			name:     "strings",
+			input:    `(f "a \"b\" c")`,
+			expected: []string{"(", "f", `"a \"b\" c"`, ")"},
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := Tokenize(tc.input)
-			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.expected, tokenValues(tc.input))
 		})
 	}
 }
+
+func TestTokenizeWithPositions(t *testing.T) {
+	tokens := TokenizeWithPositions("test.skt", "(+ 1\n\t  abc) ; end")
+
+	var actual []string
+	for _, token := range tokens {
+		actual = append(actual, fmt.Sprintf("%s@%s", token.Value, token.Pos))
+	}
+	expected := []string{
+		"(@test.skt:1:1",
+		"+@test.skt:1:2",
+		"1@test.skt:1:4",
+		"abc@test.skt:2:4",
+		")@test.skt:2:7",
+		"; end@test.skt:2:9",
+	}
+	assert.Equal(t, expected, actual)
+}
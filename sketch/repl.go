@@ -0,0 +1,409 @@
+package sketch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/jamesroutley/sketch/sketch/environment"
+	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/evaluator"
+	"github.com/jamesroutley/sketch/sketch/printer"
+	"github.com/jamesroutley/sketch/sketch/reader"
+	"github.com/jamesroutley/sketch/sketch/types"
+)
+
+const (
+	replPrompt = "user> "
+	// replContinuationPrompt is shown while the user is part way through
+	// typing a form which spans multiple lines
+	replContinuationPrompt = " ...> "
+)
+
+// DefaultHistoryFile returns the file the REPL saves its history to by
+// default: $XDG_STATE_HOME/sketch/history if $XDG_STATE_HOME is set, else
+// ~/.sketchhistory. Returns an empty string, which disables history, if
+// neither $XDG_STATE_HOME or $HOME is set.
+func DefaultHistoryFile() string {
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "sketch", "history")
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".sketchhistory")
+	}
+	return ""
+}
+
+// Repl runs the REPL, reading input from stdin until it's closed. History is
+// saved to `historyFile`, unless it's empty.
+func Repl(historyFile string) error {
+	session, err := newReplSession()
+	if err != nil {
+		return err
+	}
+
+	if historyFile != "" {
+		if err := os.MkdirAll(filepath.Dir(historyFile), 0700); err != nil {
+			return err
+		}
+	}
+
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:       replPrompt,
+		HistoryFile:  historyFile,
+		AutoComplete: session,
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			// Ctrl-C discards any partially typed form
+			session.reset()
+			rl.SetPrompt(replPrompt)
+			continue
+		}
+		if err != nil { // io.EOF
+			break
+		}
+		rl.SetPrompt(session.readLine(line, os.Stdout))
+	}
+	return nil
+}
+
+// replSession holds the state of a REPL session. Lines are buffered until
+// they contain only complete forms, so that forms can span multiple lines.
+type replSession struct {
+	// env is where the user's definitions are stored. It's a child of the
+	// root environment, so they can be listed separately from the builtins
+	env    *environment.Env
+	buffer strings.Builder
+}
+
+func newReplSession() (*replSession, error) {
+	root, err := evaluator.RootEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	return &replSession{env: root.ChildEnv()}, nil
+}
+
+// readLine adds `line` to the buffered input. If the input is made up of
+// complete forms, each of them is evaluated in turn, and its value, or any
+// error, is written to `w`. If the input is a REPL command, such as :doc, the
+// command is run instead. Returns the prompt to show for the next line.
+func (s *replSession) readLine(line string, w io.Writer) string {
+	s.buffer.WriteString(line)
+	s.buffer.WriteString("\n")
+	input := s.buffer.String()
+
+	command, arg := parseReplCommand(input)
+	if command == nil || command.takesCode {
+		code := input
+		if command != nil {
+			code = arg
+		}
+		if _, err := s.readForms(code); reader.IsIncomplete(err) {
+			return replContinuationPrompt
+		}
+	}
+	s.reset()
+
+	var err error
+	if command != nil {
+		err = command.run(s, strings.TrimSpace(arg), w)
+	} else {
+		err = s.evalCode(input, w, nil)
+	}
+	if err != nil {
+		errors.Fprint(w, err)
+	}
+	return replPrompt
+}
+
+// reset discards any buffered input
+func (s *replSession) reset() {
+	s.buffer.Reset()
+}
+
+func (s *replSession) readForms(code string) ([]types.SketchType, error) {
+	return reader.NewDecoder(strings.NewReader(code), "").DecodeAll()
+}
+
+// evalCode evaluates each of the forms in `code` in turn, and writes its
+// value to `w`. If `describe` is set, it's used to print each value, rather
+// than printer.PrStr.
+func (s *replSession) evalCode(code string, w io.Writer, describe func(types.SketchType) string) error {
+	forms, err := s.readForms(code)
+	if err != nil {
+		return err
+	}
+	if describe == nil {
+		describe = printer.PrStr
+	}
+
+	for _, form := range forms {
+		evaluated, err := evaluator.Eval(context.Background(), form, s.env)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, describe(evaluated))
+	}
+	return nil
+}
+
+// replCommand is a command which can be run at the REPL, such as :doc
+type replCommand struct {
+	name  string
+	usage string
+	help  string
+	// takesCode is set for commands whose argument is Sketch code, which
+	// can span multiple lines
+	takesCode bool
+	run       func(s *replSession, arg string, w io.Writer) error
+}
+
+var replCommands []*replCommand
+
+func init() {
+	replCommands = []*replCommand{
+		{
+			name:  ":doc",
+			usage: ":doc <symbol>",
+			help:  "Prints the documentation for a function or macro",
+			run:   replDoc,
+		},
+		{
+			name:      ":type",
+			usage:     ":type <expression>",
+			help:      "Prints the type of the value of an expression",
+			takesCode: true,
+			run: func(s *replSession, arg string, w io.Writer) error {
+				return s.evalCode(arg, w, func(value types.SketchType) string {
+					return value.Type()
+				})
+			},
+		},
+		{
+			name:      ":time",
+			usage:     ":time <expression>",
+			help:      "Evaluates an expression, and prints how long it took",
+			takesCode: true,
+			run:       replTime,
+		},
+		{
+			name:  ":load",
+			usage: ":load <file>",
+			help:  "Evaluates the contents of a file",
+			run:   replLoad,
+		},
+		{
+			name:  ":reset",
+			usage: ":reset",
+			help:  "Discards everything defined in this session",
+			run: func(s *replSession, arg string, w io.Writer) error {
+				session, err := newReplSession()
+				if err != nil {
+					return err
+				}
+				s.env = session.env
+				return nil
+			},
+		},
+		{
+			name:  ":env",
+			usage: ":env",
+			help:  "Lists everything defined in this session",
+			run:   replEnv,
+		},
+		{
+			name:      ":macroexpand",
+			usage:     ":macroexpand <expression>",
+			help:      "Prints the expansion of a macro call, without evaluating it",
+			takesCode: true,
+			run:       replMacroexpand,
+		},
+		{
+			name:  ":help",
+			usage: ":help",
+			help:  "Lists the REPL commands",
+			run: func(s *replSession, arg string, w io.Writer) error {
+				for _, command := range replCommands {
+					fmt.Fprintf(w, "%-26s %s\n", command.usage, command.help)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// parseReplCommand checks whether `input` is a REPL command. If so, it returns
+// the command, and the rest of the input, which is the command's argument.
+func parseReplCommand(input string) (*replCommand, string) {
+	trimmed := strings.TrimLeft(input, " \t")
+	name := trimmed
+	if i := strings.IndexAny(trimmed, " \t\n"); i >= 0 {
+		name = trimmed[:i]
+	}
+	for _, command := range replCommands {
+		if command.name == name {
+			return command, trimmed[len(name):]
+		}
+	}
+	return nil, ""
+}
+
+func replDoc(s *replSession, arg string, w io.Writer) error {
+	if arg == "" {
+		return fmt.Errorf(":doc expects a symbol")
+	}
+	form, err := reader.Read(arg)
+	if err != nil {
+		return err
+	}
+	value, err := evaluator.Eval(context.Background(), form, s.env)
+	if err != nil {
+		return err
+	}
+
+	function, ok := value.(*types.SketchFunction)
+	if !ok {
+		return fmt.Errorf("%s is a %s, not a function", arg, value.Type())
+	}
+
+	signature := make([]string, 0, len(function.Params)+1)
+	signature = append(signature, arg)
+	for _, param := range function.Params {
+		signature = append(signature, param.Value)
+	}
+	fmt.Fprintf(w, "(%s)\n", strings.Join(signature, " "))
+	if function.Docs == "" {
+		fmt.Fprintln(w, "  No documentation")
+		return nil
+	}
+	for _, line := range strings.Split(function.Docs, "\n") {
+		fmt.Fprintf(w, "  %s\n", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+func replTime(s *replSession, arg string, w io.Writer) error {
+	start := time.Now()
+	err := s.evalCode(arg, w, nil)
+	fmt.Fprintf(w, "Elapsed time: %s\n", time.Since(start))
+	return err
+}
+
+func replLoad(s *replSession, arg string, w io.Writer) error {
+	if arg == "" {
+		return fmt.Errorf(":load expects a file name")
+	}
+	f, err := os.Open(arg)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := evaluator.EvalProgram(context.Background(), reader.NewDecoder(f, arg), s.env); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Loaded %s\n", arg)
+	return nil
+}
+
+func replEnv(s *replSession, arg string, w io.Writer) error {
+	bindings := s.env.Bindings()
+	symbols := make([]string, 0, len(bindings))
+	for symbol := range bindings {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		fmt.Fprintf(w, "%s %s\n", symbol, printer.PrStr(bindings[symbol]))
+	}
+	return nil
+}
+
+func replMacroexpand(s *replSession, arg string, w io.Writer) error {
+	forms, err := s.readForms(arg)
+	if err != nil {
+		return err
+	}
+	for _, form := range forms {
+		expanded, err := evaluator.Eval(context.Background(), &types.SketchList{
+			List: types.NewList([]types.SketchType{
+				&types.SketchSymbol{Value: "macroexpand"},
+				form,
+			}),
+		}, s.env)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, printer.PrettyPrint(expanded))
+	}
+	return nil
+}
+
+// Do implements readline.AutoCompleter. It completes the symbol being typed
+// before the cursor with the symbols bound in the session's environment and
+// the special forms. After `module.`, it completes the module's exported
+// members. At the start of the line, it also completes REPL commands.
+func (s *replSession) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isSymbolRune(line[start-1]) {
+		start--
+	}
+	prefix := string(line[start:pos])
+
+	var candidates []string
+	if module, member, ok := strings.Cut(prefix, "."); ok {
+		for _, name := range s.moduleMembers(module) {
+			candidates = append(candidates, module+"."+name)
+		}
+		prefix = module + "." + member
+	} else {
+		candidates = append(candidates, s.env.Symbols()...)
+		candidates = append(candidates, evaluator.SpecialForms...)
+		if strings.TrimSpace(string(line[:start])) == "" && s.buffer.Len() == 0 {
+			for _, command := range replCommands {
+				candidates = append(candidates, command.name)
+			}
+		}
+	}
+	sort.Strings(candidates)
+
+	var completions [][]rune
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) && candidate != prefix {
+			completions = append(completions, []rune(candidate[len(prefix):]))
+		}
+	}
+	return completions, len([]rune(prefix))
+}
+
+// moduleMembers returns the names exported by the module bound to `name`
+func (s *replSession) moduleMembers(name string) []string {
+	value, err := s.env.Get(name)
+	if err != nil {
+		return nil
+	}
+	module, ok := value.(*types.SketchModule)
+	if !ok {
+		return nil
+	}
+	return module.Exported
+}
+
+// isSymbolRune returns whether `r` can be part of a symbol
+func isSymbolRune(r rune) bool {
+	return !strings.ContainsRune(" \t\n,()[]{}'\"`~@;", r)
+}
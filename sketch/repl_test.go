@@ -0,0 +1,213 @@
+package sketch
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type replLine struct {
+	input          string
+	expectedPrompt string
+	expectedOutput string
+}
+
+func runReplLines(t *testing.T, lines []replLine) {
+	t.Helper()
+	session, err := newReplSession()
+	require.NoError(t, err)
+
+	for _, line := range lines {
+		var output bytes.Buffer
+		prompt := session.readLine(line.input, &output)
+		assert.Equal(t, line.expectedPrompt, prompt, line.input)
+		assert.Equal(t, line.expectedOutput, output.String(), line.input)
+	}
+}
+
+func TestReplSession(t *testing.T) {
+	lines := []replLine{
+		{
+			input:          "(defn add",
+			expectedPrompt: replContinuationPrompt,
+		},
+		{
+			input:          `  "adds \"(\" numbers"`,
+			expectedPrompt: replContinuationPrompt,
+		},
+		{
+			input:          "  (a b)",
+			expectedPrompt: replContinuationPrompt,
+		},
+		{
+			input:          "  (+ a b))",
+			expectedPrompt: replPrompt,
+			expectedOutput: "#<function>\n",
+		},
+		{
+			input:          "(add 1 2) (add 3 4) ; comment",
+			expectedPrompt: replPrompt,
+			expectedOutput: "3\n7\n",
+		},
+		{
+			input:          "; comment",
+			expectedPrompt: replPrompt,
+		},
+		{
+			input:          "(def a 1) (undefined) (def b 2)",
+			expectedPrompt: replPrompt,
+			expectedOutput: "1\n1:12: `undefined` is undefined\n",
+		},
+		{
+			input:          "(list a ]",
+			expectedPrompt: replPrompt,
+			expectedOutput: "1:9: unexpected `]`\n1:10: unclosed `(` opened at 1:1\n",
+		},
+		{
+			input:          "(list a",
+			expectedPrompt: replContinuationPrompt,
+		},
+		{
+			input:          "  (+ a 1))",
+			expectedPrompt: replPrompt,
+			expectedOutput: "(1 2)\n",
+		},
+	}
+
+	runReplLines(t, lines)
+}
+
+func TestReplSession_Commands(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "lib.skt")
+	require.NoError(t, os.WriteFile(file, []byte("(defn double (x) (* x 2))"), 0600))
+
+	lines := []replLine{
+		{
+			input:          ":doc not",
+			expectedPrompt: replPrompt,
+			expectedOutput: "(not x)\n  not returns false if the argument is truthy, else true\n",
+		},
+		{
+			input:          ":type (list 1)",
+			expectedPrompt: replPrompt,
+			expectedOutput: "list\n",
+		},
+		{
+			input:          ":type (+ 1",
+			expectedPrompt: replContinuationPrompt,
+		},
+		{
+			input:          "  1) :a",
+			expectedPrompt: replPrompt,
+			expectedOutput: "int\nkeyword\n",
+		},
+		{
+			input:          ":macroexpand (defn f (x) x)",
+			expectedPrompt: replPrompt,
+			expectedOutput: "(def f (fn (x) x))\n",
+		},
+		{
+			input:          ":load " + file,
+			expectedPrompt: replPrompt,
+			expectedOutput: "Loaded " + file + "\n",
+		},
+		{
+			input:          "(def a (double 2))",
+			expectedPrompt: replPrompt,
+			expectedOutput: "4\n",
+		},
+		{
+			input:          ":env",
+			expectedPrompt: replPrompt,
+			expectedOutput: "a 4\ndouble #<function>\n",
+		},
+		{
+			input:          ":reset",
+			expectedPrompt: replPrompt,
+		},
+		{
+			input:          ":env",
+			expectedPrompt: replPrompt,
+		},
+		{
+			input:          ":doc a",
+			expectedPrompt: replPrompt,
+			expectedOutput: "1:1: `a` is undefined\n",
+		},
+		{
+			input:          ":a",
+			expectedPrompt: replPrompt,
+			expectedOutput: ":a\n",
+		},
+	}
+	runReplLines(t, lines)
+}
+
+func TestReplSession_Time(t *testing.T) {
+	session, err := newReplSession()
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	session.readLine(":time (+ 1 1)", &output)
+	assert.Regexp(t, `^2\nElapsed time: .+\n$`, output.String())
+}
+
+func TestReplSession_Completion(t *testing.T) {
+	session, err := newReplSession()
+	require.NoError(t, err)
+	var output bytes.Buffer
+	session.readLine(`(import "string") (def hashmap-thing 1)`, &output)
+
+	cases := []struct {
+		name     string
+		line     string
+		expected []string
+	}{
+		{
+			name:     "symbols in the environment",
+			line:     "(hashmap-",
+			expected: []string{"delete", "get", "keys", "set", "thing", "values"},
+		},
+		{
+			name:     "special forms",
+			line:     "(quasiq",
+			expected: []string{"uote", "uoteexpand"},
+		},
+		{
+			name:     "module members",
+			line:     "(string.spl",
+			expected: []string{"it"},
+		},
+		{
+			name:     "commands",
+			line:     ":ma",
+			expected: []string{"croexpand"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			line := []rune(tc.line)
+			completions, _ := session.Do(line, len(line))
+			actual := make([]string, len(completions))
+			for i, completion := range completions {
+				actual[i] = string(completion)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDefaultHistoryFile(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/state")
+	t.Setenv("HOME", "/home/user")
+	assert.Equal(t, "/state/sketch/history", DefaultHistoryFile())
+
+	t.Setenv("XDG_STATE_HOME", "")
+	assert.Equal(t, "/home/user/.sketchhistory", DefaultHistoryFile())
+}
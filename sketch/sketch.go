@@ -2,74 +2,51 @@
 package sketch
 
 import (
-	"fmt"
-	"io/ioutil"
+	"context"
+	"os"
 	"strings"
 
-	"github.com/chzyer/readline"
 	"github.com/jamesroutley/sketch/sketch/environment"
 	"github.com/jamesroutley/sketch/sketch/evaluator"
 	"github.com/jamesroutley/sketch/sketch/printer"
 	"github.com/jamesroutley/sketch/sketch/reader"
+	"github.com/jamesroutley/sketch/sketch/types"
 )
 
 func RunFile(filename string) error {
-	data, err := ioutil.ReadFile(filename)
+	f, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	ast, err := reader.Read(fmt.Sprintf("(do %s)", data))
+	env, err := evaluator.RootEnvironment()
 	if err != nil {
 		return err
 	}
 
-	_, err = evaluator.Evaluate(ast)
+	_, err = evaluator.EvalProgram(context.Background(), reader.NewDecoder(f, filename), env)
 	return err
 }
 
-func Repl() error {
-	env, err := evaluator.RootEnvironment()
+// Rep - read, evaluate, print. Every form in `s` is evaluated, and the value
+// of the last one is printed. If `s` doesn't contain any forms (e.g. it's a
+// comment), nothing is printed.
+func Rep(s string, env *environment.Env) (string, error) {
+	forms, err := reader.NewDecoder(strings.NewReader(s), "").DecodeAll()
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	rl, err := readline.NewEx(&readline.Config{
-		Prompt:      "user> ",
-		HistoryFile: "/Users/jamesroutley/.sketchhistory",
-	})
-	if err != nil {
-		return err
+	if len(forms) == 0 {
+		return "", nil
 	}
-	defer rl.Close()
 
-	for {
-		line, err := rl.Readline()
-		if err != nil { // io.EOF
-			break
-		}
-		line = strings.TrimSuffix(line, "\n")
-		output, err := Rep(line, env)
+	var evaluated types.SketchType
+	for _, form := range forms {
+		evaluated, err = evaluator.Eval(context.Background(), form, env)
 		if err != nil {
-			fmt.Println(err)
+			return "", err
 		}
-		fmt.Println(output)
-	}
-	return nil
-}
-
-// Rep - read, evaluate, print
-func Rep(s string, env *environment.Env) (string, error) {
-	ast, err := reader.Read(s)
-	if err != nil {
-		return "", err
-	}
-	evaluated, err := evaluator.Eval(ast, env)
-	if err != nil {
-		if err.Error() == "read comment" {
-			return "", nil
-		}
-		return "", err
 	}
 	return printer.PrStr(evaluated), nil
 }
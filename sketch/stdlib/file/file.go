@@ -13,7 +13,7 @@ var EnvironmentItems = map[string]types.SketchType{}
 
 func register(symbol string, f func(...types.SketchType) (types.SketchType, error)) {
 	EnvironmentItems[symbol] = &types.SketchFunction{
-		Func:      f,
+		Func:      validation.RejectErrorArgs(symbol, f),
 		BoundName: symbol,
 	}
 }
@@ -21,9 +21,14 @@ func register(symbol string, f func(...types.SketchType) (types.SketchType, erro
 func init() {
 	register("read-all", readAll)
 	register("read-lines", readLines)
+	register("try-read-all", tryReadAll)
+	register("try-read-lines", tryReadLines)
 }
 
 func readAll(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("read-all", 1, args); err != nil {
+		return nil, err
+	}
 	filename, err := validation.StringArg("read-all", args[0], 0)
 	if err != nil {
 		return nil, err
@@ -40,6 +45,9 @@ func readAll(args ...types.SketchType) (types.SketchType, error) {
 }
 
 func readLines(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("read-lines", 1, args); err != nil {
+		return nil, err
+	}
 	filename, err := validation.StringArg("read-lines", args[0], 0)
 	if err != nil {
 		return nil, err
@@ -67,3 +75,43 @@ func readLines(args ...types.SketchType) (types.SketchType, error) {
 		List: types.NewList(items),
 	}, nil
 }
+
+// tryReadAll is a variant of read-all which returns a result, rather than
+// raising an error if the file can't be read.
+func tryReadAll(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("try-read-all", 1, args); err != nil {
+		return nil, err
+	}
+	if _, err := validation.StringArg("try-read-all", args[0], 0); err != nil {
+		return nil, err
+	}
+	return toResult(readAll(args...))
+}
+
+// tryReadLines is a variant of read-lines which returns a result, rather than
+// raising an error if the file can't be read.
+func tryReadLines(args ...types.SketchType) (types.SketchType, error) {
+	if err := validation.NArgs("try-read-lines", 1, args); err != nil {
+		return nil, err
+	}
+	if _, err := validation.StringArg("try-read-lines", args[0], 0); err != nil {
+		return nil, err
+	}
+	return toResult(readLines(args...))
+}
+
+// toResult converts the return values of a function into a result. We only
+// call this after validating the function's arguments, so any error is an IO
+// error, rather than a programming error.
+func toResult(value types.SketchType, err error) (types.SketchType, error) {
+	if err != nil {
+		return &types.SketchResult{
+			Ok:    false,
+			Value: &types.SketchString{Value: err.Error()},
+		}, nil
+	}
+	return &types.SketchResult{
+		Ok:    true,
+		Value: value,
+	}, nil
+}
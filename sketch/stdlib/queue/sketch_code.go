@@ -46,7 +46,7 @@ const SketchCode = `
     (cond
       ((queue-empty? q) q)
       ((not (empty? front)) q) ; Front isn't empty - don't do anything
-      ("else" (list (reverse back) (list))))))
+      (:else (list (reverse back) (list))))))
 
 (defn
   put
@@ -63,7 +63,7 @@ const SketchCode = `
     (cond
       ((queue-empty? q) (error "Can't peek an empty queue"))
       ((empty? front) (head (rebalance q)))
-      ("else" (first front)))))
+      (:else (first front)))))
 
 (defn
   tail
@@ -74,7 +74,7 @@ const SketchCode = `
     (cond
       ((queue-empty? q) (error "Can't tail an empty queue"))
       ((empty? front) (tail (rebalance q)))
-      ("else" (list (rest front) back)))))
+      (:else (list (rest front) back)))))
 
 (defn len (q) (reduce + (map length q)))
 
@@ -11,7 +11,7 @@ var EnvironmentItems = map[string]types.SketchType{}
 
 func register(symbol string, f func(...types.SketchType) (types.SketchType, error)) {
 	EnvironmentItems[symbol] = &types.SketchFunction{
-		Func:      f,
+		Func:      validation.RejectErrorArgs(symbol, f),
 		BoundName: symbol,
 	}
 }
@@ -15,7 +15,7 @@ const SketchCode = `
   (cond
     ((empty? elements) "") ; Special behaviour if called with an empty list
     ((empty? (rest elements)) (first elements)) ; Recursion base case
-    ("else" (+ (first elements) separator (join (rest elements) separator)))))
+    (:else (+ (first elements) separator (join (rest elements) separator)))))
 
 (export-as string (join))
 `
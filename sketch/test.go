@@ -1,8 +1,9 @@
 package sketch
 
 import (
+	"context"
 	"fmt"
-	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/jamesroutley/sketch/sketch/environment"
@@ -16,14 +17,11 @@ func TestFile(filename string) error {
 	// function's docstring.
 
 	// First, evaluate the file
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return err
-	}
-	ast, err := reader.Read(fmt.Sprintf("(do %s)", data))
+	f, err := os.Open(filename)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 	env, err := evaluator.RootEnvironment()
 	if err != nil {
 		return err
@@ -32,13 +30,13 @@ func TestFile(filename string) error {
 	// Evaluate the file in a new child env this lets us just test items added
 	// by the file
 	child := env.ChildEnv()
-	if _, err := evaluator.Eval(ast, child); err != nil {
+	if _, err := evaluator.EvalProgram(context.Background(), reader.NewDecoder(f, filename), child); err != nil {
 		return err
 	}
 
 	// Then, iterate through any functions, and run docstring tests, if any are
 	// specified
-	for key, value := range child.Data {
+	for key, value := range child.Bindings() {
 		function, ok := value.(*types.SketchFunction)
 		if !ok {
 			continue
@@ -82,7 +80,7 @@ func runDocstringTest(name string, env *environment.Env, function *types.SketchF
 		if err != nil {
 			return err
 		}
-		actual, err := evaluator.Eval(ast, env)
+		actual, err := evaluator.Eval(context.Background(), ast, env)
 		if err != nil {
 			return err
 		}
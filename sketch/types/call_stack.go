@@ -1,11 +1,92 @@
 package types
 
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxFrameArgs is the number of arguments a StackFrame keeps. Calls with
+	// more arguments only record the first MaxFrameArgs
+	MaxFrameArgs = 8
+	// maxFrameArgLength is the number of characters of each argument which
+	// are printed by StackFrame.String
+	maxFrameArgLength = 24
+)
+
+// StackFrame is a function call which was in progress when an error
+// happened.
 type StackFrame struct {
-	Next         *StackFrame
+	// Next is the frame of the call this call made, which was in progress when
+	// the error happened. It's nil for the innermost frame
+	Next *StackFrame
+	// FunctionName is the name the function was called by
 	FunctionName string
+	// Pos is the position of the form which made the call
+	Pos Position
+	// Args are the values the function was called with, truncated to the
+	// first MaxFrameArgs
+	Args []SketchType
+	// NumArgs is the number of arguments the function was called with,
+	// including any which weren't kept in Args
+	NumArgs int
+	// TailCalls is the number of frames which tail call optimisation elided
+	// before this one. When a function makes a tail call, its frame is
+	// replaced by the frame of the function it calls
+	TailCalls int
+}
+
+// NewStackFrame returns the frame for a call to the function called `name`
+// with `args`, made by the form at `pos`.
+func NewStackFrame(name string, args []SketchType, pos Position) *StackFrame {
+	frame := &StackFrame{
+		FunctionName: name,
+		Pos:          pos,
+		Args:         args,
+		NumArgs:      len(args),
+	}
+	if len(args) > MaxFrameArgs {
+		frame.Args = args[:MaxFrameArgs]
+	}
+	return frame
 }
 
+// String returns the frame in the form `(f 1 "abc") at 3:2`. Long arguments
+// are shortened.
+func (f *StackFrame) String() string {
+	call := []string{f.FunctionName}
+	for _, arg := range f.Args {
+		call = append(call, truncate(arg.String(), maxFrameArgLength))
+	}
+	if f.NumArgs > len(f.Args) {
+		call = append(call, "...")
+	}
+
+	s := fmt.Sprintf("(%s)", strings.Join(call, " "))
+	if f.Pos.IsValid() {
+		s = fmt.Sprintf("%s at %s", s, f.Pos)
+	}
+	if f.TailCalls > 0 {
+		s = fmt.Sprintf("%s, after %d tail calls", s, f.TailCalls)
+	}
+	return s
+}
+
+// truncate shortens `s` to at most `n` characters, replacing the end with an
+// ellipsis if it's too long.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-3]) + "..."
+}
+
+// CallStack is the list of function calls which led to an error. It's
+// immutable: pushing a frame returns a new CallStack, so call stacks can share
+// their inner frames.
 type CallStack struct {
+	// Head is the outermost frame
 	Head *StackFrame
 }
 
@@ -13,9 +94,26 @@ func NewCallStack() *CallStack {
 	return &CallStack{}
 }
 
+// Push returns a new call stack, with `frame` as the outermost frame. The
+// frame mustn't already be part of a call stack.
 func (c *CallStack) Push(frame *StackFrame) *CallStack {
-	frame.Next = c.Head
+	if c != nil {
+		frame.Next = c.Head
+	}
 	return &CallStack{
 		Head: frame,
 	}
 }
+
+// Frames returns the frames in the call stack, outermost first. A nil
+// CallStack has no frames.
+func (c *CallStack) Frames() []*StackFrame {
+	if c == nil {
+		return nil
+	}
+	var frames []*StackFrame
+	for frame := c.Head; frame != nil; frame = frame.Next {
+		frames = append(frames, frame)
+	}
+	return frames
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStackFrame_String(t *testing.T) {
+	frame := NewStackFrame("f", []SketchType{
+		&SketchInt{Value: 1},
+		&SketchString{Value: strings.Repeat("a", 40)},
+	}, Position{Line: 3, Column: 2})
+	assert.Equal(t, `(f 1 "aaaaaaaaaaaaaaaaaaaa...) at 3:2`, frame.String())
+
+	frame.TailCalls = 5
+	assert.Equal(t, `(f 1 "aaaaaaaaaaaaaaaaaaaa...) at 3:2, after 5 tail calls`, frame.String())
+}
+
+func TestNewStackFrame_TruncatesArgs(t *testing.T) {
+	args := make([]SketchType, MaxFrameArgs+2)
+	for i := range args {
+		args[i] = &SketchInt{Value: i}
+	}
+
+	frame := NewStackFrame("g", args, Position{})
+	assert.Len(t, frame.Args, MaxFrameArgs)
+	assert.Equal(t, MaxFrameArgs+2, frame.NumArgs)
+	assert.Equal(t, "(g 0 1 2 3 4 5 6 7 ...)", frame.String())
+}
+
+func TestCallStack_Push(t *testing.T) {
+	inner := NewCallStack().Push(NewStackFrame("inner", nil, Position{}))
+	outer := inner.Push(NewStackFrame("outer", nil, Position{}))
+
+	var names []string
+	for _, frame := range outer.Frames() {
+		names = append(names, frame.FunctionName)
+	}
+	assert.Equal(t, []string{"outer", "inner"}, names)
+	// Pushing doesn't change the original stack
+	assert.Len(t, inner.Frames(), 1)
+
+	var empty *CallStack
+	assert.Empty(t, empty.Frames())
+}
@@ -0,0 +1,87 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capability is a set of things Sketch code can do which affect the world
+// outside the interpreter, such as reading files. Builtins declare the
+// capabilities they need, and a sandboxed environment only lets code call
+// builtins whose capabilities it has been granted.
+type Capability uint
+
+const (
+	// ReadFiles lets code read files, e.g. with slurp
+	ReadFiles Capability = 1 << iota
+	// WriteFiles lets code create, change and delete files
+	WriteFiles
+	// EnvVars lets code read and set environment variables
+	EnvVars
+	// Exec lets code run other processes
+	Exec
+	// ImportModules lets code import modules from disk. Importing a stdlib
+	// module only needs the capabilities that module declares
+	ImportModules
+
+	// NoCapabilities is the empty set of capabilities. Code with no
+	// capabilities can only compute values, and use the IO streams it's given
+	NoCapabilities Capability = 0
+	// AllCapabilities is every capability. Environments which aren't
+	// sandboxed have every capability
+	AllCapabilities = ReadFiles | WriteFiles | EnvVars | Exec | ImportModules
+)
+
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{ReadFiles, "read-files"},
+	{WriteFiles, "write-files"},
+	{EnvVars, "env-vars"},
+	{Exec, "exec"},
+	{ImportModules, "import-modules"},
+}
+
+// Has returns whether `c` contains every capability in `required`.
+func (c Capability) Has(required Capability) bool {
+	return required&^c == 0
+}
+
+// Missing returns the capabilities in `required` which `c` doesn't contain.
+func (c Capability) Missing(required Capability) Capability {
+	return required &^ c
+}
+
+// String returns the names of the capabilities in the set, e.g.
+// "read-files, exec".
+func (c Capability) String() string {
+	var names []string
+	for _, capability := range capabilityNames {
+		if c.Has(capability.capability) {
+			names = append(names, capability.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// PermissionError is returned when sandboxed code tries to do something it
+// doesn't have the capabilities for.
+type PermissionError struct {
+	// Name is the name of the builtin or module which needs the capabilities
+	Name    string
+	Missing Capability
+}
+
+func (e *PermissionError) Error() string {
+	noun := "capability"
+	if strings.Contains(e.Missing.String(), ",") {
+		noun = "capabilities"
+	}
+	return fmt.Sprintf(
+		"permission denied: %s needs the %s %s, which this environment doesn't grant",
+		e.Name, e.Missing, noun)
+}
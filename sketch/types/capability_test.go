@@ -0,0 +1,28 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapability(t *testing.T) {
+	granted := ReadFiles | ImportModules
+
+	assert.True(t, granted.Has(ReadFiles))
+	assert.True(t, granted.Has(NoCapabilities))
+	assert.False(t, granted.Has(ReadFiles|Exec))
+	assert.True(t, AllCapabilities.Has(granted))
+	assert.Equal(t, Exec, granted.Missing(ReadFiles|Exec))
+
+	assert.Equal(t, "read-files, import-modules", granted.String())
+	assert.Equal(t, "none", NoCapabilities.String())
+}
+
+func TestPermissionError(t *testing.T) {
+	err := &PermissionError{Name: "slurp", Missing: ReadFiles}
+	assert.EqualError(t, err, "permission denied: slurp needs the read-files capability, which this environment doesn't grant")
+
+	err = &PermissionError{Name: "run", Missing: Exec | EnvVars}
+	assert.EqualError(t, err, "permission denied: run needs the env-vars, exec capabilities, which this environment doesn't grant")
+}
@@ -0,0 +1,130 @@
+package types
+
+import (
+	"math"
+	"strings"
+)
+
+// Compare defines a total ordering over Sketch values. It returns -1, 0 or 1,
+// depending on whether a sorts before, the same as, or after b.
+//
+// Values of different types are ordered by type: nil, then booleans, numbers,
+// keywords, strings, symbols, lists and vectors, and sets. Numbers are ordered
+// by value, regardless of their type, keywords, strings and symbols
+// lexicographically, and lists, vectors and sets item by item. Lists and
+// vectors are ordered together, so a list and a vector with equal items, which
+// are equal according to `=`, compare the same.
+// Values of other types sort after these, ordered by their type's name and
+// then their printed representation.
+//
+// Compare is used to give hash maps a deterministic print and iteration
+// order.
+func Compare(a, b SketchType) int {
+	aRank, bRank := typeRank(a), typeRank(b)
+	if aRank != bRank {
+		return compareInts(aRank, bRank)
+	}
+
+	switch a := a.(type) {
+	case *SketchNil:
+		return 0
+	case *SketchBoolean:
+		b := b.(*SketchBoolean)
+		if a.Value == b.Value {
+			return 0
+		}
+		if !a.Value {
+			return -1
+		}
+		return 1
+	case *SketchKeyword:
+		return strings.Compare(a.Name(), b.(*SketchKeyword).Name())
+	case *SketchString:
+		return strings.Compare(a.Value, b.(*SketchString).Value)
+	case *SketchSymbol:
+		return strings.Compare(a.Value, b.(*SketchSymbol).Value)
+	case *SketchList, *SketchVector:
+		aItems, _ := SequenceItems(a)
+		bItems, _ := SequenceItems(b)
+		return compareSlices(aItems, bItems)
+	case *SketchSet:
+		return compareSlices(a.Items(), b.(*SketchSet).Items())
+	}
+
+	if IsNumber(a) {
+		return compareNumbers(a, b)
+	}
+
+	if cmp := strings.Compare(a.Type(), b.Type()); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// typeRank returns the position of a value's type in the ordering defined by
+// Compare.
+func typeRank(t SketchType) int {
+	switch t.(type) {
+	case *SketchNil:
+		return 0
+	case *SketchBoolean:
+		return 1
+	case *SketchInt, *SketchBigInt, *SketchRatio, *SketchFloat:
+		return 2
+	case *SketchKeyword:
+		return 3
+	case *SketchString:
+		return 4
+	case *SketchSymbol:
+		return 5
+	case *SketchList, *SketchVector:
+		return 6
+	case *SketchSet:
+		return 7
+	}
+	return 8
+}
+
+// compareNumbers compares two numbers by value. Numbers with an exact value
+// are compared exactly. NaN sorts before every other number.
+func compareNumbers(a, b SketchType) int {
+	aExact, aOK := exactValue(a)
+	bExact, bOK := exactValue(b)
+	if aOK && bOK {
+		return aExact.Cmp(bExact)
+	}
+
+	aFloat, bFloat := ToFloat64(a), ToFloat64(b)
+	switch {
+	case math.IsNaN(aFloat) && math.IsNaN(bFloat):
+		return 0
+	case math.IsNaN(aFloat):
+		return -1
+	case math.IsNaN(bFloat):
+		return 1
+	case aFloat < bFloat:
+		return -1
+	case aFloat > bFloat:
+		return 1
+	}
+	return 0
+}
+
+func compareSlices(a, b []SketchType) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if cmp := Compare(a[i], b[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return compareInts(len(a), len(b))
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
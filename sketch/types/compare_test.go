@@ -0,0 +1,55 @@
+package types
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	// Each value sorts strictly before the next
+	ordered := []SketchType{
+		&SketchNil{},
+		&SketchBoolean{Value: false},
+		&SketchBoolean{Value: true},
+		&SketchFloat{Value: math.NaN()},
+		&SketchFloat{Value: math.Inf(-1)},
+		&SketchInt{Value: -1},
+		&SketchRatio{Value: big.NewRat(1, 3)},
+		&SketchFloat{Value: 0.5},
+		&SketchInt{Value: 1},
+		NewBigInt(new(big.Int).Lsh(big.NewInt(1), 100)),
+		&SketchFloat{Value: math.Inf(1)},
+		&SketchString{Value: ""},
+		&SketchString{Value: "a"},
+		&SketchString{Value: "b"},
+		&SketchSymbol{Value: "a"},
+		&SketchList{List: NewList(nil)},
+		&SketchList{List: NewList([]SketchType{&SketchInt{Value: 1}})},
+		&SketchVector{Vector: NewVector([]SketchType{&SketchInt{Value: 1}, &SketchInt{Value: 2}})},
+		&SketchList{List: NewList([]SketchType{&SketchInt{Value: 2}})},
+		&SketchVector{Vector: NewVector([]SketchType{&SketchInt{Value: 3}})},
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			expected := compareInts(i, j)
+			assert.Equal(t, expected, Compare(ordered[i], ordered[j]), "Compare(%s, %s)", ordered[i], ordered[j])
+		}
+	}
+}
+
+func TestCompare_EqualNumbers(t *testing.T) {
+	assert.Equal(t, 0, Compare(&SketchInt{Value: 1}, &SketchFloat{Value: 1}))
+	assert.Equal(t, 0, Compare(&SketchRatio{Value: big.NewRat(1, 2)}, &SketchFloat{Value: 0.5}))
+}
+
+func TestCompare_EqualSequences(t *testing.T) {
+	list := &SketchList{List: NewList([]SketchType{&SketchInt{Value: 1}, &SketchString{Value: "a"}})}
+	vector := &SketchVector{Vector: NewVector([]SketchType{&SketchFloat{Value: 1}, &SketchString{Value: "a"}})}
+	assert.Equal(t, 0, Compare(list, vector))
+	assert.Equal(t, 0, Compare(vector, list))
+	assert.Equal(t, hashMapKey(list), hashMapKey(vector))
+}
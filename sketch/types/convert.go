@@ -0,0 +1,451 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+var (
+	sketchTypeType = reflect.TypeOf((*SketchType)(nil)).Elem()
+	bigIntType     = reflect.TypeOf((*big.Int)(nil))
+	bigRatType     = reflect.TypeOf((*big.Rat)(nil))
+)
+
+// FromGo converts the Go value `v` to a Sketch value:
+//
+//   - nil and nil pointers become nil
+//   - bools become booleans
+//   - ints and uints become ints, or big ints if they don't fit in an int.
+//     *big.Int and *big.Rat values become ints and ratios
+//   - floats become floats
+//   - strings become strings
+//   - slices and arrays become vectors
+//   - maps become hash maps
+//   - structs become hash maps, with a keyword key for each exported field
+//
+// Struct fields can be configured with a `sketch` tag, in the same format as
+// the encoding/json package's tags. `sketch:"name"` sets the field's key to
+// :name, `sketch:",omitempty"` leaves the field out if it has its zero value
+// and `sketch:"-"` always leaves it out. By default, a field's key is its name
+// in kebab case, so the field FirstName has the key :first-name.
+//
+// Values which are already Sketch values are returned unchanged. Like
+// encoding/json, FromGo returns an error for values which contain themselves,
+// such as a linked list node which points to itself.
+func FromGo(v interface{}) (SketchType, error) {
+	if v == nil {
+		return &SketchNil{}, nil
+	}
+	return fromGo(reflect.ValueOf(v), map[visit]bool{})
+}
+
+// visit identifies a pointer, map or slice which fromGo is converting. Slices
+// which share an array but have different lengths are different values, so
+// the length is part of the key.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// fromGo converts `v` to a Sketch value. `visiting` holds the pointers, maps
+// and slices which contain `v`, so cycles can be detected: converting a
+// value which contains itself would otherwise recurse until the Go stack
+// overflowed, which can't be recovered from.
+func fromGo(v reflect.Value, visiting map[visit]bool) (SketchType, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !v.IsNil() {
+			key := visit{ptr: v.Pointer(), typ: v.Type()}
+			if v.Kind() == reflect.Slice {
+				key.len = v.Len()
+			}
+			if visiting[key] {
+				return nil, fmt.Errorf("cannot convert Go value of type %s to a Sketch value: it contains itself", v.Type())
+			}
+			visiting[key] = true
+			defer delete(visiting, key)
+		}
+	}
+
+	isNil := (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil()
+	if v.Type().Implements(sketchTypeType) && !isNil {
+		return v.Interface().(SketchType), nil
+	}
+
+	switch v.Type() {
+	case bigIntType:
+		if v.IsNil() {
+			return &SketchNil{}, nil
+		}
+		return NewBigInt(new(big.Int).Set(v.Interface().(*big.Int))), nil
+	case bigRatType:
+		if v.IsNil() {
+			return &SketchNil{}, nil
+		}
+		return NewRatio(new(big.Rat).Set(v.Interface().(*big.Rat))), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &SketchNil{}, nil
+		}
+		return fromGo(v.Elem(), visiting)
+	case reflect.Bool:
+		return &SketchBoolean{Value: v.Bool()}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NewBigInt(big.NewInt(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewBigInt(new(big.Int).SetUint64(v.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return &SketchFloat{Value: v.Float()}, nil
+	case reflect.String:
+		return &SketchString{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return &SketchVector{Vector: NewEmptyVector()}, nil
+		}
+		items := make([]SketchType, v.Len())
+		for i := range items {
+			item, err := fromGo(v.Index(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return &SketchVector{Vector: NewVector(items)}, nil
+	case reflect.Map:
+		m, _ := NewSketchHashMap(nil)
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := fromGo(iter.Key(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			if err := ValidHashMapKey(key); err != nil {
+				return nil, err
+			}
+			value, err := fromGo(iter.Value(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Set(key, value)
+		}
+		return m, nil
+	case reflect.Struct:
+		m, _ := NewSketchHashMap(nil)
+		for _, field := range structFields(v.Type()) {
+			fieldValue := v.Field(field.index)
+			if field.omitEmpty && fieldValue.IsZero() {
+				continue
+			}
+			value, err := fromGo(fieldValue, visiting)
+			if err != nil {
+				return nil, err
+			}
+			m = m.Set(NewKeyword(field.name), value)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot convert Go value of type %s to a Sketch value", v.Type())
+}
+
+// ToGo converts the Sketch value `value` to a Go value, and stores it in the
+// value `target` points to. It's the inverse of FromGo: hash maps can be
+// stored in maps or structs, and lists, vectors and sets in slices or arrays.
+// Numbers can be stored in any numeric type they fit in without losing
+// precision, apart from floats, which any number can be stored in. Keywords
+// can be stored in strings.
+//
+// If `target` points to an empty interface, the value is stored as a bool,
+// int, *big.Int, *big.Rat, float64, string, []interface{} or
+// map[interface{}]interface{}, or nil. If it points to a Sketch type, the
+// value is stored unchanged.
+func ToGo(value SketchType, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("ToGo: target must be a non-nil pointer, got %T", target)
+	}
+	return toGo(value, v.Elem())
+}
+
+func toGo(value SketchType, target reflect.Value) error {
+	t := target.Type()
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		goValue, err := naturalGoValue(value)
+		if err != nil {
+			return err
+		}
+		if goValue == nil {
+			target.Set(reflect.Zero(t))
+			return nil
+		}
+		target.Set(reflect.ValueOf(goValue))
+		return nil
+	}
+	if reflect.TypeOf(value).AssignableTo(t) {
+		target.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	if _, ok := value.(*SketchNil); ok {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			target.Set(reflect.Zero(t))
+			return nil
+		}
+		return conversionError(value, t)
+	}
+
+	switch t {
+	case bigIntType:
+		switch value.(type) {
+		case *SketchInt, *SketchBigInt:
+			target.Set(reflect.ValueOf(new(big.Int).Set(ToBigInt(value))))
+			return nil
+		}
+		return conversionError(value, t)
+	case bigRatType:
+		switch value.(type) {
+		case *SketchInt, *SketchBigInt, *SketchRatio:
+			target.Set(reflect.ValueOf(new(big.Rat).Set(ToRat(value))))
+			return nil
+		}
+		return conversionError(value, t)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(t.Elem())
+		if err := toGo(value, elem.Elem()); err != nil {
+			return err
+		}
+		target.Set(elem)
+		return nil
+	case reflect.Bool:
+		b, ok := value.(*SketchBoolean)
+		if !ok {
+			return conversionError(value, t)
+		}
+		target.SetBool(b.Value)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := integerValue(value)
+		if !ok || !i.IsInt64() || target.OverflowInt(i.Int64()) {
+			return conversionError(value, t)
+		}
+		target.SetInt(i.Int64())
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := integerValue(value)
+		if !ok || !i.IsUint64() || target.OverflowUint(i.Uint64()) {
+			return conversionError(value, t)
+		}
+		target.SetUint(i.Uint64())
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if !IsNumber(value) {
+			return conversionError(value, t)
+		}
+		f := ToFloat64(value)
+		if t.Kind() == reflect.Float32 && !math.IsInf(f, 0) && target.OverflowFloat(f) {
+			return conversionError(value, t)
+		}
+		target.SetFloat(f)
+		return nil
+	case reflect.String:
+		switch value := value.(type) {
+		case *SketchString:
+			target.SetString(value.Value)
+			return nil
+		case *SketchKeyword:
+			target.SetString(value.Name())
+			return nil
+		}
+		return conversionError(value, t)
+	case reflect.Slice:
+		items, ok := SequenceItems(value)
+		if !ok {
+			return conversionError(value, t)
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			if err := toGo(item, slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+		return nil
+	case reflect.Array:
+		items, ok := SequenceItems(value)
+		if !ok || len(items) != t.Len() {
+			return conversionError(value, t)
+		}
+		for i, item := range items {
+			if err := toGo(item, target.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		hashMap, ok := value.(*SketchHashMap)
+		if !ok {
+			return conversionError(value, t)
+		}
+		m := reflect.MakeMapWithSize(t, hashMap.Len())
+		for _, item := range hashMap.sortedItems() {
+			key := reflect.New(t.Key()).Elem()
+			if err := toGo(item.key, key); err != nil {
+				return err
+			}
+			mapValue := reflect.New(t.Elem()).Elem()
+			if err := toGo(item.value, mapValue); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, mapValue)
+		}
+		target.Set(m)
+		return nil
+	case reflect.Struct:
+		hashMap, ok := value.(*SketchHashMap)
+		if !ok {
+			return conversionError(value, t)
+		}
+		for _, field := range structFields(t) {
+			// Fields can be keyed by keywords or strings
+			fieldValue, err := hashMap.Get(NewKeyword(field.name))
+			if err != nil {
+				fieldValue, err = hashMap.Get(&SketchString{Value: field.name})
+				if err != nil {
+					continue
+				}
+			}
+			if err := toGo(fieldValue, target.Field(field.index)); err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+		}
+		return nil
+	}
+	return conversionError(value, t)
+}
+
+// naturalGoValue returns the Go value `value` is converted to when it's
+// stored in an empty interface.
+func naturalGoValue(value SketchType) (interface{}, error) {
+	switch value := value.(type) {
+	case *SketchNil:
+		return nil, nil
+	case *SketchBoolean:
+		return value.Value, nil
+	case *SketchInt:
+		return value.Value, nil
+	case *SketchBigInt:
+		return new(big.Int).Set(value.Value), nil
+	case *SketchRatio:
+		return new(big.Rat).Set(value.Value), nil
+	case *SketchFloat:
+		return value.Value, nil
+	case *SketchString:
+		return value.Value, nil
+	case *SketchKeyword:
+		return value.Name(), nil
+	case *SketchList, *SketchVector, *SketchSet:
+		items, _ := SequenceItems(value)
+		slice := make([]interface{}, len(items))
+		for i, item := range items {
+			goItem, err := naturalGoValue(item)
+			if err != nil {
+				return nil, err
+			}
+			slice[i] = goItem
+		}
+		return slice, nil
+	case *SketchHashMap:
+		m := make(map[interface{}]interface{}, value.Len())
+		for _, item := range value.sortedItems() {
+			key, err := naturalGoValue(item.key)
+			if err != nil {
+				return nil, err
+			}
+			if key != nil && !reflect.TypeOf(key).Comparable() {
+				return nil, fmt.Errorf("cannot convert hash map key %s to a Go map key", item.key)
+			}
+			mapValue, err := naturalGoValue(item.value)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = mapValue
+		}
+		return m, nil
+	}
+	return value, nil
+}
+
+// integerValue returns the value of `value` if it's an integer.
+func integerValue(value SketchType) (*big.Int, bool) {
+	switch value.(type) {
+	case *SketchInt, *SketchBigInt:
+		return ToBigInt(value), true
+	}
+	return nil, false
+}
+
+func conversionError(value SketchType, t reflect.Type) error {
+	return fmt.Errorf("cannot convert %s `%s` to Go type %s", value.Type(), value, t)
+}
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// structFields returns the exported fields of the struct type `t` which
+// aren't skipped with a `sketch:"-"` tag.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := field.Tag.Get("sketch")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = kebabCase(field.Name)
+		}
+		fields = append(fields, structField{
+			index:     i,
+			name:      name,
+			omitEmpty: options == "omitempty",
+		})
+	}
+	return fields
+}
+
+// kebabCase converts a Go identifier, such as HTTPStatusCode, to kebab case:
+// http-status-code.
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(previous) || nextIsLower {
+				b.WriteRune('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,203 @@
+package types
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPerson struct {
+	FirstName string
+	Age       int    `sketch:"years"`
+	Email     string `sketch:",omitempty"`
+	Password  string `sketch:"-"`
+	Scores    []int
+	Partner   *testPerson
+	private   bool
+}
+
+func TestFromGo(t *testing.T) {
+	var nilPointer *testPerson
+	cases := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"nil", nil, "nil"},
+		{"nil pointer", nilPointer, "nil"},
+		{"bool", true, "true"},
+		{"int", 42, "42"},
+		{"int8", int8(-3), "-3"},
+		{"uint64 which overflows an int", uint64(math.MaxUint64), "18446744073709551615"},
+		{"float", 1.5, "1.5"},
+		{"string", "a \"b\"", `"a \"b\""`},
+		{"big int", new(big.Int).Lsh(big.NewInt(1), 100), "1267650600228229401496703205376"},
+		{"big rat", big.NewRat(1, 3), "1/3"},
+		{"slice", []string{"a", "b"}, `["a" "b"]`},
+		{"nil slice", []int(nil), "[]"},
+		{"array", [2]bool{true, false}, "[true false]"},
+		{"map", map[string]int{"b": 2, "a": 1}, `{"a" 1 "b" 2}`},
+		{"nested", map[int][]interface{}{1: {nil, 2.5}}, "{1 [nil 2.5]}"},
+		{"sketch value", NewKeyword("a"), ":a"},
+		{
+			"struct",
+			testPerson{FirstName: "Ada", Age: 36, Password: "secret", Scores: []int{1}},
+			`{:first-name "Ada" :partner nil :scores [1] :years 36}`,
+		},
+		{
+			"struct pointer",
+			&testPerson{FirstName: "Ada", Email: "ada@example.com"},
+			`{:email "ada@example.com" :first-name "Ada" :partner nil :scores [] :years 0}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := FromGo(tc.input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual.String())
+		})
+	}
+}
+
+func TestFromGo_Errors(t *testing.T) {
+	for _, input := range []interface{}{
+		func() {},
+		make(chan int),
+		map[interface{}]int{nil: 1},
+	} {
+		_, err := FromGo(input)
+		assert.Error(t, err, "%#v", input)
+	}
+}
+
+func TestFromGo_Cycles(t *testing.T) {
+	type node struct {
+		Value int
+		Next  *node
+	}
+	n := &node{Value: 1}
+	n.Next = n
+	_, err := FromGo(n)
+	assert.EqualError(t, err, "cannot convert Go value of type *types.node to a Sketch value: it contains itself")
+
+	m := map[string]interface{}{}
+	m["self"] = m
+	_, err = FromGo(m)
+	assert.Error(t, err)
+
+	s := []interface{}{nil}
+	s[0] = s
+	_, err = FromGo(s)
+	assert.Error(t, err)
+
+	// Values which are shared, but don't contain themselves, aren't cycles
+	shared := &node{Value: 2}
+	actual, err := FromGo([]*node{shared, shared})
+	require.NoError(t, err)
+	assert.Equal(t, "[{:next nil :value 2} {:next nil :value 2}]", actual.String())
+}
+
+func TestToGo(t *testing.T) {
+	var i int
+	require.NoError(t, ToGo(&SketchInt{Value: 3}, &i))
+	assert.Equal(t, 3, i)
+
+	var u8 uint8
+	require.NoError(t, ToGo(&SketchInt{Value: 255}, &u8))
+	assert.Equal(t, uint8(255), u8)
+	assert.Error(t, ToGo(&SketchInt{Value: 256}, &u8))
+	assert.Error(t, ToGo(&SketchInt{Value: -1}, &u8))
+	assert.Error(t, ToGo(&SketchFloat{Value: 1}, &u8))
+
+	var f float64
+	require.NoError(t, ToGo(NewRatio(big.NewRat(1, 4)), &f))
+	assert.Equal(t, 0.25, f)
+
+	var s string
+	require.NoError(t, ToGo(NewKeyword("a"), &s))
+	assert.Equal(t, "a", s)
+	assert.Error(t, ToGo(&SketchInt{Value: 1}, &s))
+
+	var b *big.Int
+	require.NoError(t, ToGo(&SketchInt{Value: 7}, &b))
+	assert.Equal(t, big.NewInt(7), b)
+
+	var strings []string
+	list := &SketchList{List: NewList([]SketchType{&SketchString{Value: "a"}, &SketchString{Value: "b"}})}
+	require.NoError(t, ToGo(list, &strings))
+	assert.Equal(t, []string{"a", "b"}, strings)
+
+	var pair [2]int
+	assert.Error(t, ToGo(list, &pair))
+
+	m, err := NewSketchHashMap([]SketchType{NewKeyword("a"), &SketchInt{Value: 1}})
+	require.NoError(t, err)
+	var goMap map[string]int
+	require.NoError(t, ToGo(m, &goMap))
+	assert.Equal(t, map[string]int{"a": 1}, goMap)
+
+	var sketchString *SketchString
+	require.NoError(t, ToGo(&SketchString{Value: "a"}, &sketchString))
+	assert.Equal(t, "a", sketchString.Value)
+
+	assert.Error(t, ToGo(&SketchInt{Value: 1}, i))
+}
+
+func TestToGo_Interface(t *testing.T) {
+	m, err := NewSketchHashMap([]SketchType{
+		NewKeyword("a"), &SketchVector{Vector: NewVector([]SketchType{&SketchInt{Value: 1}, &SketchNil{}})},
+		&SketchString{Value: "b"}, NewRatio(big.NewRat(1, 2)),
+	})
+	require.NoError(t, err)
+
+	var actual interface{}
+	require.NoError(t, ToGo(m, &actual))
+	assert.Equal(t, map[interface{}]interface{}{
+		"a": []interface{}{1, nil},
+		"b": big.NewRat(1, 2),
+	}, actual)
+}
+
+func TestToGo_Struct(t *testing.T) {
+	person := testPerson{
+		FirstName: "Ada",
+		Age:       36,
+		Scores:    []int{1, 2},
+		Partner:   &testPerson{FirstName: "Bob"},
+	}
+	sketchValue, err := FromGo(person)
+	require.NoError(t, err)
+
+	var roundTripped testPerson
+	require.NoError(t, ToGo(sketchValue, &roundTripped))
+	person.Partner.Scores = []int{}
+	assert.Equal(t, person, roundTripped)
+
+	// Fields can be keyed by strings too, and missing fields are left alone
+	m, err := NewSketchHashMap([]SketchType{&SketchString{Value: "first-name"}, &SketchString{Value: "Cy"}})
+	require.NoError(t, err)
+	partial := testPerson{Age: 3}
+	require.NoError(t, ToGo(m, &partial))
+	assert.Equal(t, testPerson{FirstName: "Cy", Age: 3}, partial)
+
+	m, err = NewSketchHashMap([]SketchType{NewKeyword("years"), &SketchString{Value: "old"}})
+	require.NoError(t, err)
+	assert.Error(t, ToGo(m, &partial))
+}
+
+func TestKebabCase(t *testing.T) {
+	cases := map[string]string{
+		"Name":           "name",
+		"FirstName":      "first-name",
+		"HTTPStatusCode": "http-status-code",
+		"UserID":         "user-id",
+		"V2Config":       "v2-config",
+	}
+	for input, expected := range cases {
+		assert.Equal(t, expected, kebabCase(input), input)
+	}
+}
@@ -0,0 +1,311 @@
+package types
+
+import "math/bits"
+
+// hamt implements an immutable, persistent hash array mapped trie. It's the
+// datastructure underlying SketchHashMap.
+//
+// Each node in the trie has up to 32 children, indexed by 5 bits of the key's
+// hash. Rather than storing 32 child pointers, each node stores a bitmap
+// recording which children are present, and a dense slice of just those
+// children. Updates copy the path from the root to the changed node, and
+// share everything else with the original trie, so Set, Get and Delete are
+// all O(log32 n).
+type hamt struct {
+	root *hamtNode
+	size int
+}
+
+const (
+	hamtBitsPerLevel = 5
+	hamtMask         = 1<<hamtBitsPerLevel - 1
+)
+
+type hamtNode struct {
+	bitmap  uint32
+	entries []*hamtEntry
+}
+
+// hamtEntry is either a child node or a leaf. Exactly one of the two fields
+// is set. Storing pointers to entries, rather than entries themselves, halves
+// the amount of memory copied on each update.
+type hamtEntry struct {
+	node *hamtNode
+	leaf *hamtLeaf
+}
+
+// hamtLeaf stores every item whose key has the hash `hash`. There's usually
+// only one, but there can be more if the hashes of two keys collide.
+type hamtLeaf struct {
+	hash  uint64
+	items []*hashMapValue
+}
+
+func newHAMT() *hamt {
+	return &hamt{
+		root: &hamtNode{},
+	}
+}
+
+// hashString returns the 64 bit FNV-1a hash of `s`. We implement it here,
+// rather than using hash/fnv, to avoid allocating.
+func hashString(s string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	hash := uint64(offset)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint64(s[i])
+		hash *= prime
+	}
+	return hash
+}
+
+// index returns the position of the child at `shift` for `hash` in the node's
+// bitmap, and in its dense slice of entries.
+func (n *hamtNode) index(hash uint64, shift uint) (bit uint32, position int) {
+	bit = 1 << ((hash >> shift) & hamtMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// Len returns the number of items in the trie
+func (h *hamt) Len() int {
+	return h.size
+}
+
+// Get returns the item stored under `mapKey`
+func (h *hamt) Get(mapKey string) (*hashMapValue, bool) {
+	hash := hashString(mapKey)
+	node := h.root
+	for shift := uint(0); ; shift += hamtBitsPerLevel {
+		bit, position := node.index(hash, shift)
+		if node.bitmap&bit == 0 {
+			return nil, false
+		}
+		entry := node.entries[position]
+		if entry.node != nil {
+			node = entry.node
+			continue
+		}
+		if entry.leaf.hash != hash {
+			return nil, false
+		}
+		for _, item := range entry.leaf.items {
+			if item.mapKey == mapKey {
+				return item, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// Set returns a new trie, with `item` stored under its key
+func (h *hamt) Set(item *hashMapValue) *hamt {
+	root, added := h.root.set(hashString(item.mapKey), 0, item)
+	size := h.size
+	if added {
+		size++
+	}
+	return &hamt{
+		root: root,
+		size: size,
+	}
+}
+
+// Delete returns a new trie, without the item stored under `mapKey`
+func (h *hamt) Delete(mapKey string) *hamt {
+	root, removed := h.root.delete(hashString(mapKey), 0, mapKey)
+	if !removed {
+		return h
+	}
+	if root == nil {
+		root = &hamtNode{}
+	}
+	return &hamt{
+		root: root,
+		size: h.size - 1,
+	}
+}
+
+// Each calls `f` with each item in the trie
+func (h *hamt) Each(f func(item *hashMapValue)) {
+	h.root.each(f)
+}
+
+func (n *hamtNode) each(f func(item *hashMapValue)) {
+	for _, entry := range n.entries {
+		if entry.node != nil {
+			entry.node.each(f)
+			continue
+		}
+		for _, item := range entry.leaf.items {
+			f(item)
+		}
+	}
+}
+
+// set returns a copy of the node with `item` added, and whether the item was
+// added, rather than replacing an existing item with the same key.
+func (n *hamtNode) set(hash uint64, shift uint, item *hashMapValue) (*hamtNode, bool) {
+	bit, position := n.index(hash, shift)
+
+	if n.bitmap&bit == 0 {
+		entries := make([]*hamtEntry, len(n.entries)+1)
+		copy(entries, n.entries[:position])
+		entries[position] = &hamtEntry{leaf: &hamtLeaf{
+			hash:  hash,
+			items: []*hashMapValue{item},
+		}}
+		copy(entries[position+1:], n.entries[position:])
+		return &hamtNode{
+			bitmap:  n.bitmap | bit,
+			entries: entries,
+		}, true
+	}
+
+	var replacement *hamtEntry
+	added := true
+	switch entry := n.entries[position]; {
+	case entry.node != nil:
+		var child *hamtNode
+		child, added = entry.node.set(hash, shift+hamtBitsPerLevel, item)
+		replacement = &hamtEntry{node: child}
+	case entry.leaf.hash == hash:
+		var leaf *hamtLeaf
+		leaf, added = entry.leaf.set(item)
+		replacement = &hamtEntry{leaf: leaf}
+	default:
+		// The slot is taken by a leaf with a different hash, so we push both
+		// leaves down into a new node
+		newLeaf := &hamtLeaf{
+			hash:  hash,
+			items: []*hashMapValue{item},
+		}
+		replacement = &hamtEntry{
+			node: mergeLeaves(entry.leaf, newLeaf, shift+hamtBitsPerLevel),
+		}
+	}
+
+	return n.replace(position, replacement), added
+}
+
+// mergeLeaves returns a node containing the leaves `a` and `b`, which must
+// have different hashes.
+func mergeLeaves(a, b *hamtLeaf, shift uint) *hamtNode {
+	aIndex := (a.hash >> shift) & hamtMask
+	bIndex := (b.hash >> shift) & hamtMask
+	if aIndex == bIndex {
+		return &hamtNode{
+			bitmap:  1 << aIndex,
+			entries: []*hamtEntry{{node: mergeLeaves(a, b, shift+hamtBitsPerLevel)}},
+		}
+	}
+	if aIndex > bIndex {
+		a, b = b, a
+	}
+	return &hamtNode{
+		bitmap:  1<<aIndex | 1<<bIndex,
+		entries: []*hamtEntry{{leaf: a}, {leaf: b}},
+	}
+}
+
+// delete returns a copy of the node without the item stored under `mapKey`,
+// and whether the item was found. If the resulting node would be empty, nil
+// is returned.
+func (n *hamtNode) delete(hash uint64, shift uint, mapKey string) (*hamtNode, bool) {
+	bit, position := n.index(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+
+	var replacement *hamtEntry
+	switch entry := n.entries[position]; {
+	case entry.node != nil:
+		child, removed := entry.node.delete(hash, shift+hamtBitsPerLevel, mapKey)
+		if !removed {
+			return n, false
+		}
+		switch {
+		case child == nil:
+			return n.remove(position, bit), true
+		case len(child.entries) == 1 && child.entries[0].leaf != nil:
+			// Don't leave chains of nodes which only contain a single leaf
+			replacement = child.entries[0]
+		default:
+			replacement = &hamtEntry{node: child}
+		}
+	case entry.leaf.hash == hash:
+		leaf, removed := entry.leaf.delete(mapKey)
+		if !removed {
+			return n, false
+		}
+		if leaf == nil {
+			return n.remove(position, bit), true
+		}
+		replacement = &hamtEntry{leaf: leaf}
+	default:
+		return n, false
+	}
+
+	return n.replace(position, replacement), true
+}
+
+// replace returns a copy of the node, with the entry at `position` replaced
+func (n *hamtNode) replace(position int, entry *hamtEntry) *hamtNode {
+	entries := make([]*hamtEntry, len(n.entries))
+	copy(entries, n.entries)
+	entries[position] = entry
+	return &hamtNode{
+		bitmap:  n.bitmap,
+		entries: entries,
+	}
+}
+
+// remove returns a copy of the node, with the entry at `position` removed.
+// Returns nil if the node would be empty.
+func (n *hamtNode) remove(position int, bit uint32) *hamtNode {
+	if len(n.entries) == 1 {
+		return nil
+	}
+	entries := make([]*hamtEntry, 0, len(n.entries)-1)
+	entries = append(entries, n.entries[:position]...)
+	entries = append(entries, n.entries[position+1:]...)
+	return &hamtNode{
+		bitmap:  n.bitmap &^ bit,
+		entries: entries,
+	}
+}
+
+// set returns a copy of the leaf with `item` added, and whether it was added,
+// rather than replacing an existing item with the same key.
+func (l *hamtLeaf) set(item *hashMapValue) (*hamtLeaf, bool) {
+	items := make([]*hashMapValue, len(l.items), len(l.items)+1)
+	copy(items, l.items)
+	for i, existing := range items {
+		if existing.mapKey == item.mapKey {
+			items[i] = item
+			return &hamtLeaf{hash: l.hash, items: items}, false
+		}
+	}
+	return &hamtLeaf{hash: l.hash, items: append(items, item)}, true
+}
+
+// delete returns a copy of the leaf without the item stored under `mapKey`,
+// and whether the item was found. If the resulting leaf would be empty, nil
+// is returned.
+func (l *hamtLeaf) delete(mapKey string) (*hamtLeaf, bool) {
+	for i, item := range l.items {
+		if item.mapKey != mapKey {
+			continue
+		}
+		if len(l.items) == 1 {
+			return nil, true
+		}
+		items := make([]*hashMapValue, 0, len(l.items)-1)
+		items = append(items, l.items[:i]...)
+		items = append(items, l.items[i+1:]...)
+		return &hamtLeaf{hash: l.hash, items: items}, true
+	}
+	return l, false
+}
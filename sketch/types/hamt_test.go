@@ -0,0 +1,96 @@
+package types
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHAMT(t *testing.T) {
+	// Apply a random sequence of sets and deletes to a trie and to a Go map,
+	// and check they always agree
+	rng := rand.New(rand.NewSource(1))
+	expected := map[string]int{}
+	trie := newHAMT()
+	for i := 0; i < 20000; i++ {
+		mapKey := fmt.Sprint(rng.Intn(5000))
+		if rng.Intn(3) == 0 {
+			delete(expected, mapKey)
+			trie = trie.Delete(mapKey)
+		} else {
+			expected[mapKey] = i
+			trie = trie.Set(&hashMapValue{
+				mapKey: mapKey,
+				value:  &SketchInt{Value: i},
+			})
+		}
+	}
+
+	require.Equal(t, len(expected), trie.Len())
+	for mapKey, value := range expected {
+		item, ok := trie.Get(mapKey)
+		require.True(t, ok, mapKey)
+		assert.Equal(t, value, item.value.(*SketchInt).Value)
+	}
+	seen := 0
+	trie.Each(func(item *hashMapValue) {
+		seen++
+		assert.Contains(t, expected, item.mapKey)
+	})
+	assert.Equal(t, len(expected), seen)
+}
+
+func TestHAMT_Persistence(t *testing.T) {
+	original := newHAMT().Set(&hashMapValue{mapKey: "a", value: &SketchInt{Value: 1}})
+	updated := original.Set(&hashMapValue{mapKey: "a", value: &SketchInt{Value: 2}})
+	deleted := updated.Delete("a")
+
+	item, _ := original.Get("a")
+	assert.Equal(t, 1, item.value.(*SketchInt).Value)
+	item, _ = updated.Get("a")
+	assert.Equal(t, 2, item.value.(*SketchInt).Value)
+	_, ok := deleted.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, updated.Len())
+	assert.Equal(t, 0, deleted.Len())
+}
+
+func TestHAMT_Collisions(t *testing.T) {
+	// Two items in a leaf are stored, replaced and deleted independently
+	leaf := &hamtLeaf{hash: 1}
+	leaf, added := leaf.set(&hashMapValue{mapKey: "a"})
+	assert.True(t, added)
+	leaf, added = leaf.set(&hashMapValue{mapKey: "b"})
+	assert.True(t, added)
+	leaf, added = leaf.set(&hashMapValue{mapKey: "a"})
+	assert.False(t, added)
+	require.Len(t, leaf.items, 2)
+
+	leaf, removed := leaf.delete("a")
+	assert.True(t, removed)
+	require.Len(t, leaf.items, 1)
+	assert.Equal(t, "b", leaf.items[0].mapKey)
+}
+
+// BenchmarkHashMap_Build100k builds a 100,000 item hash map one item at a
+// time, as Sketch code does with hashmap-set.
+func BenchmarkHashMap_Build100k(b *testing.B) {
+	keys := make([]SketchType, 100000)
+	for i := range keys {
+		keys[i] = &SketchInt{Value: i}
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		m, _ := NewSketchHashMap(nil)
+		for _, key := range keys {
+			m = m.Set(key, key)
+		}
+		if m.Len() != len(keys) {
+			b.Fatalf("expected %d items, got %d", len(keys), m.Len())
+		}
+	}
+}
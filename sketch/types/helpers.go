@@ -1,11 +1,92 @@
 package types
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 func ValidHashMapKey(arg SketchType) error {
 	switch arg.(type) {
-	case *SketchInt, *SketchString, *SketchSymbol, *SketchList, *SketchBoolean:
+	case *SketchInt, *SketchBigInt, *SketchRatio, *SketchFloat, *SketchString, *SketchSymbol, *SketchKeyword, *SketchList, *SketchVector, *SketchSet, *SketchBoolean:
 		return nil
 	}
 	return fmt.Errorf("hash map argument %s has type %s - can't use this as a hash map key", arg.String(), arg.Type())
 }
+
+// hashMapKey returns the key used to store `key` in a hash map's underlying
+// trie. Values which are equal, as defined by `=`, have the same key. Numbers
+// which are equal are stored under the same key, regardless of their type, so
+// 1, 1.0 and a big int with the value 1 are the same key, as are 1/2 and 0.5.
+// Lists and vectors with equal items are the same key, as are sets with
+// equal items.
+func hashMapKey(key SketchType) string {
+	switch key := key.(type) {
+	case *SketchKeyword:
+		return key.keyword.mapKey
+	case *SketchInt:
+		// Fast path for the most common kind of number
+		return "number" + strconv.Itoa(key.Value)
+	case *SketchList:
+		return compositeKey("sequence", itemKeys(key.List.ToSlice()))
+	case *SketchVector:
+		return compositeKey("sequence", itemKeys(key.Vector.ToSlice()))
+	case *SketchSet:
+		// Sets are unordered, so the items' keys are sorted
+		keys := itemKeys(key.Items())
+		sort.Strings(keys)
+		return compositeKey("set", keys)
+	}
+	if r, ok := exactValue(key); ok {
+		return "number" + r.RatString()
+	}
+	return key.Type() + key.String()
+}
+
+func itemKeys(items []SketchType) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = hashMapKey(item)
+	}
+	return keys
+}
+
+// compositeKey builds the key of a collection from the keys of its items.
+// Each item's key is prefixed with its length, so the boundaries between
+// items are unambiguous.
+func compositeKey(kind string, keys []string) string {
+	var b strings.Builder
+	b.WriteString(kind)
+	b.WriteByte('(')
+	for _, key := range keys {
+		b.WriteString(strconv.Itoa(len(key)))
+		b.WriteByte(':')
+		b.WriteString(key)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// SequenceItems returns the items in `t`, if it's a list, vector or set. A
+// set's items are returned in the order defined by Compare.
+func SequenceItems(t SketchType) ([]SketchType, bool) {
+	switch t := t.(type) {
+	case *SketchList:
+		return t.List.ToSlice(), true
+	case *SketchVector:
+		return t.Vector.ToSlice(), true
+	case *SketchSet:
+		return t.Items(), true
+	}
+	return nil, false
+}
+
+// NewSequenceLike returns a new sequence containing `items`, of the same type
+// as `like`: a vector if `like` is a vector, otherwise a list.
+func NewSequenceLike(like SketchType, items []SketchType) SketchType {
+	if _, ok := like.(*SketchVector); ok {
+		return &SketchVector{Vector: NewVector(items)}
+	}
+	return &SketchList{List: NewList(items)}
+}
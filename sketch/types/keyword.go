@@ -0,0 +1,107 @@
+package types
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SketchKeyword is a keyword, written as :name. Keywords evaluate to
+// themselves, which makes them convenient hash map keys.
+//
+// Keywords are interned: every keyword with the same name shares the same
+// underlying *keyword, so comparing and hashing keywords doesn't need to look
+// at their names.
+type SketchKeyword struct {
+	keyword *keyword
+	Pos     Position
+}
+
+type keyword struct {
+	name string
+	// mapKey is the key returned by hashMapKey for the keyword. We compute
+	// it once, when the keyword is interned
+	mapKey string
+}
+
+var (
+	keywordsMu sync.Mutex
+	keywords   = map[string]*keyword{}
+)
+
+// NewKeyword returns the keyword with the name `name`. The name doesn't
+// include the leading colon.
+func NewKeyword(name string) *SketchKeyword {
+	keywordsMu.Lock()
+	defer keywordsMu.Unlock()
+
+	k, ok := keywords[name]
+	if !ok {
+		k = &keyword{
+			name:   name,
+			mapKey: "keyword:" + name,
+		}
+		keywords[name] = k
+	}
+	return &SketchKeyword{
+		keyword: k,
+	}
+}
+
+// Name returns the keyword's name, without the leading colon
+func (k *SketchKeyword) Name() string {
+	return k.keyword.name
+}
+
+// Equals returns whether two keywords are the same keyword
+func (k *SketchKeyword) Equals(other *SketchKeyword) bool {
+	return k.keyword == other.keyword
+}
+
+func (k *SketchKeyword) String() string {
+	return ":" + k.keyword.name
+}
+
+func (k *SketchKeyword) Type() string {
+	return "keyword"
+}
+
+// Call looks the keyword up in the hash map passed as the first argument.
+// Like hashmap-get, it takes an optional default value, which is returned if
+// the map doesn't contain the keyword.
+//
+// > (:name {:name "Sketch"})
+// "Sketch"
+func (k *SketchKeyword) Call(args []SketchType) (SketchType, error) {
+	if numArgs := len(args); numArgs < 1 || numArgs > 2 {
+		return nil, fmt.Errorf("the function %s expects between 1 and 2 arguments, but got %d", k, numArgs)
+	}
+	hashMap, ok := args[0].(*SketchHashMap)
+	if !ok {
+		return nil, fmt.Errorf(
+			"the function %s expects the 1st argument `%s` to be type hashmap, got type %s",
+			k, args[0], args[0].Type())
+	}
+
+	value, err := hashMap.Get(k)
+	if err != nil {
+		if len(args) == 2 {
+			return args[1], nil
+		}
+		return nil, err
+	}
+	return value, nil
+}
+
+// Function returns a function which calls the keyword, so keywords can be
+// passed to functions which expect a function, such as map.
+//
+// > (map :name (list {:name "Sketch"}))
+// ("Sketch")
+func (k *SketchKeyword) Function() *SketchFunction {
+	return &SketchFunction{
+		Func: func(args ...SketchType) (SketchType, error) {
+			return k.Call(args)
+		},
+		BoundName: k.String(),
+	}
+}
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyword_Interning(t *testing.T) {
+	a := NewKeyword("a")
+	a.Pos = Position{Line: 1, Column: 1}
+	otherA := NewKeyword("a")
+
+	assert.True(t, a.Equals(otherA))
+	assert.Same(t, a.keyword, otherA.keyword)
+	assert.False(t, a.Equals(NewKeyword("b")))
+	assert.Equal(t, hashMapKey(a), hashMapKey(otherA))
+	assert.NotEqual(t, hashMapKey(a), hashMapKey(&SketchString{Value: "a"}))
+}
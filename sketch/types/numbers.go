@@ -0,0 +1,145 @@
+package types
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// SketchBigInt is an arbitrary precision integer. Sketch's maths functions
+// automatically promote ints to big ints when a result would overflow, and
+// demote them back when the result fits in an int. This means a SketchBigInt's
+// value never fits in an int - use NewBigInt to construct one.
+type SketchBigInt struct {
+	Value *big.Int
+	Pos   Position
+}
+
+// NewBigInt returns an int with the value of `i`. If `i` fits in a
+// SketchInt, a SketchInt is returned. Otherwise, a SketchBigInt is returned.
+func NewBigInt(i *big.Int) SketchType {
+	if i.IsInt64() && int64(int(i.Int64())) == i.Int64() {
+		return &SketchInt{Value: int(i.Int64())}
+	}
+	return &SketchBigInt{Value: i}
+}
+
+func (i *SketchBigInt) String() string {
+	return i.Value.String()
+}
+
+func (i *SketchBigInt) Type() string {
+	return "bigint"
+}
+
+// SketchRatio is an exact rational number, such as 1/3. Dividing two ints
+// returns a ratio if the division isn't exact. A SketchRatio's value is never
+// an integer - use NewRatio to construct one.
+type SketchRatio struct {
+	Value *big.Rat
+	Pos   Position
+}
+
+// NewRatio returns a number with the value of `r`. If `r` is an integer, an
+// int is returned. Otherwise, a SketchRatio is returned.
+func NewRatio(r *big.Rat) SketchType {
+	if r.IsInt() {
+		return NewBigInt(new(big.Int).Set(r.Num()))
+	}
+	return &SketchRatio{Value: r}
+}
+
+func (r *SketchRatio) String() string {
+	return r.Value.String()
+}
+
+func (r *SketchRatio) Type() string {
+	return "ratio"
+}
+
+// SketchFloat is a floating point number
+type SketchFloat struct {
+	Value float64
+	Pos   Position
+}
+
+func (f *SketchFloat) String() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	// Make sure floats with integral values are printed so they're read back
+	// in as floats, not ints
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+func (f *SketchFloat) Type() string {
+	return "float"
+}
+
+// IsNumber returns whether `t` is one of Sketch's numeric types.
+func IsNumber(t SketchType) bool {
+	switch t.(type) {
+	case *SketchInt, *SketchBigInt, *SketchRatio, *SketchFloat:
+		return true
+	}
+	return false
+}
+
+// ToFloat64 converts the number `n` to a float64. It panics if `n` isn't a
+// number.
+func ToFloat64(n SketchType) float64 {
+	switch n := n.(type) {
+	case *SketchInt:
+		return float64(n.Value)
+	case *SketchBigInt:
+		f, _ := new(big.Float).SetInt(n.Value).Float64()
+		return f
+	case *SketchRatio:
+		f, _ := n.Value.Float64()
+		return f
+	case *SketchFloat:
+		return n.Value
+	}
+	panic("ToFloat64 called with a non-numeric type " + n.Type())
+}
+
+// ToBigInt converts the int or big int `n` to a *big.Int. It panics if `n`
+// isn't an integer.
+func ToBigInt(n SketchType) *big.Int {
+	switch n := n.(type) {
+	case *SketchInt:
+		return big.NewInt(int64(n.Value))
+	case *SketchBigInt:
+		return n.Value
+	}
+	panic("ToBigInt called with a non-integer type " + n.Type())
+}
+
+// ToRat converts the int, big int or ratio `n` to a *big.Rat. It panics if
+// `n` isn't one of those types.
+func ToRat(n SketchType) *big.Rat {
+	switch n := n.(type) {
+	case *SketchInt, *SketchBigInt:
+		return new(big.Rat).SetInt(ToBigInt(n))
+	case *SketchRatio:
+		return n.Value
+	}
+	panic("ToRat called with a non-rational type " + n.Type())
+}
+
+// exactValue returns the exact value of the number `n` as a *big.Rat.
+// Infinite and NaN floats don't have an exact value.
+func exactValue(n SketchType) (*big.Rat, bool) {
+	switch n := n.(type) {
+	case *SketchInt, *SketchBigInt, *SketchRatio:
+		return ToRat(n), true
+	case *SketchFloat:
+		if math.IsInf(n.Value, 0) || math.IsNaN(n.Value) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(n.Value), true
+	}
+	return nil, false
+}
@@ -0,0 +1,69 @@
+package types
+
+import "fmt"
+
+// Position describes where in the source code a form was read from. Forms
+// which weren't read from source code (e.g. values created while evaluating
+// a program) have the zero Position.
+type Position struct {
+	// File is the name of the file the form was read from. It's empty if the
+	// form wasn't read from a file (e.g. it was typed into the REPL)
+	File string
+	// Line and Column are 1-indexed. Column counts characters, not bytes
+	Line   int
+	Column int
+}
+
+// IsValid returns whether the position refers to a real location in source
+// code.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form file.skt:12:5. If the file isn't
+// known, the position is returned in the form 12:5.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// PositionOf returns the position the form `ast` was read from. Returns the
+// zero Position if the form wasn't read from source code.
+func PositionOf(ast SketchType) Position {
+	switch ast := ast.(type) {
+	case *SketchList:
+		return ast.Pos
+	case *SketchVector:
+		return ast.Pos
+	case *SketchHashMap:
+		return ast.Pos
+	case *SketchSet:
+		return ast.Pos
+	case *SketchInt:
+		return ast.Pos
+	case *SketchBigInt:
+		return ast.Pos
+	case *SketchRatio:
+		return ast.Pos
+	case *SketchFloat:
+		return ast.Pos
+	case *SketchSymbol:
+		return ast.Pos
+	case *SketchKeyword:
+		return ast.Pos
+	case *SketchBoolean:
+		return ast.Pos
+	case *SketchNil:
+		return ast.Pos
+	case *SketchString:
+		return ast.Pos
+	case *SketchComment:
+		return ast.Pos
+	}
+	return Position{}
+}
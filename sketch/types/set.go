@@ -0,0 +1,101 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SketchSet is an immutable, persistent set, written as #{1 2 3}. It's backed
+// by the same hash array mapped trie as SketchHashMap, and its items must be
+// valid hash map keys. Like hash maps, sets are printed and iterated over in
+// the order defined by Compare.
+type SketchSet struct {
+	items *hamt
+	Pos   Position
+}
+
+// NewSketchSet returns a set containing `items`. Duplicate items are ignored.
+func NewSketchSet(items []SketchType) (*SketchSet, error) {
+	s := &SketchSet{
+		items: newHAMT(),
+	}
+	for _, item := range items {
+		var err error
+		s, err = s.Add(item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *SketchSet) String() string {
+	items := s.Items()
+	itemStrings := make([]string, len(items))
+	for i, item := range items {
+		itemStrings[i] = item.String()
+	}
+	return fmt.Sprintf("#{%s}", strings.Join(itemStrings, " "))
+}
+
+func (s *SketchSet) Type() string {
+	return "set"
+}
+
+// Add returns a new set, containing `item`
+func (s *SketchSet) Add(item SketchType) (*SketchSet, error) {
+	if err := validSetItem(item); err != nil {
+		return nil, err
+	}
+	return &SketchSet{
+		items: s.items.Set(&hashMapValue{
+			mapKey: hashMapKey(item),
+			key:    item,
+		}),
+	}, nil
+}
+
+// Remove returns a new set, without `item`. If the set doesn't contain
+// `item`, the set is returned unchanged.
+func (s *SketchSet) Remove(item SketchType) (*SketchSet, error) {
+	if err := validSetItem(item); err != nil {
+		return nil, err
+	}
+	return &SketchSet{
+		items: s.items.Delete(hashMapKey(item)),
+	}, nil
+}
+
+// Contains returns whether the set contains `item`
+func (s *SketchSet) Contains(item SketchType) (bool, error) {
+	if err := validSetItem(item); err != nil {
+		return false, err
+	}
+	_, ok := s.items.Get(hashMapKey(item))
+	return ok, nil
+}
+
+// Len returns the number of items in the set
+func (s *SketchSet) Len() int {
+	return s.items.Len()
+}
+
+// Items returns the set's items, in the order defined by Compare
+func (s *SketchSet) Items() []SketchType {
+	items := make([]SketchType, 0, s.items.Len())
+	s.items.Each(func(item *hashMapValue) {
+		items = append(items, item.key)
+	})
+	sort.Slice(items, func(i, j int) bool {
+		return Compare(items[i], items[j]) < 0
+	})
+	return items
+}
+
+func validSetItem(item SketchType) error {
+	if err := ValidHashMapKey(item); err != nil {
+		return fmt.Errorf("set item %s has type %s - can't add this to a set", item, item.Type())
+	}
+	return nil
+}
@@ -2,9 +2,12 @@
 package types
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 type SketchType interface {
@@ -21,6 +24,7 @@ type EnvType interface {
 
 type SketchList struct {
 	List *List
+	Pos  Position
 }
 
 func (l *SketchList) String() string {
@@ -37,21 +41,49 @@ func (l *SketchList) Type() string {
 }
 
 type hashMapValue struct {
-	key   SketchType
-	value SketchType
-}
-
+	// mapKey is the key returned by hashMapKey, which the item is stored under
+	mapKey string
+	key    SketchType
+	value  SketchType
+	// insertion records when the key was first added to the map. It's used to
+	// order insertion ordered maps
+	insertion int
+}
+
+// SketchHashMap is an immutable, persistent hash map. Updating a hash map
+// returns a new map, which shares most of its structure with the original.
+//
+// By default, a hash map's keys are printed and iterated over in the order
+// defined by Compare. Insertion ordered maps, created with
+// NewOrderedSketchHashMap, use the order keys were first added instead.
 type SketchHashMap struct {
-	// TODO: make this private
-	Items map[string]*hashMapValue
+	items *hamt
+	// ordered is true if the map is insertion ordered
+	ordered bool
+	// nextInsertion is the insertion number given to the next new key
+	nextInsertion int
+	Pos           Position
 }
 
 func NewSketchHashMap(items []SketchType) (*SketchHashMap, error) {
+	return newSketchHashMap(items, false)
+}
+
+// NewOrderedSketchHashMap returns a hash map which keeps its keys in the
+// order they were first added.
+func NewOrderedSketchHashMap(items []SketchType) (*SketchHashMap, error) {
+	return newSketchHashMap(items, true)
+}
+
+func newSketchHashMap(items []SketchType, ordered bool) (*SketchHashMap, error) {
 	if numArgs := len(items); numArgs%2 != 0 {
 		return nil, fmt.Errorf("maps must be instantiated with an even number of arguments, got %d", numArgs)
 	}
 
-	mapItems := map[string]*hashMapValue{}
+	m := &SketchHashMap{
+		items:   newHAMT(),
+		ordered: ordered,
+	}
 	for i := 0; i < len(items); i += 2 {
 		key := items[i]
 		value := items[i+1]
@@ -60,23 +92,16 @@ func NewSketchHashMap(items []SketchType) (*SketchHashMap, error) {
 			return nil, err
 		}
 
-		hashMakKey := key.Type() + key.String()
-		mapItems[hashMakKey] = &hashMapValue{
-			key:   key,
-			value: value,
-		}
+		m = m.Set(key, value)
 	}
 
-	return &SketchHashMap{
-		Items: mapItems,
-	}, nil
+	return m, nil
 }
 
 func (m *SketchHashMap) String() string {
 	var items []string
-	// TODO: stabilize print order
-	for _, value := range m.Items {
-		items = append(items, value.key.String(), value.value.String())
+	for _, item := range m.sortedItems() {
+		items = append(items, item.key.String(), item.value.String())
 	}
 	return fmt.Sprintf("{%s}", strings.Join(items, " "))
 }
@@ -85,20 +110,34 @@ func (m *SketchHashMap) Type() string {
 	return "hashmap"
 }
 
+// Ordered returns whether the map is insertion ordered
+func (m *SketchHashMap) Ordered() bool {
+	return m.ordered
+}
+
+// Set returns a new map, with `key` set to `value`. The key must be valid, as
+// reported by ValidHashMapKey.
 func (m *SketchHashMap) Set(key, value SketchType) *SketchHashMap {
-	mapItems := map[string]*hashMapValue{}
-	for k, v := range m.Items {
-		mapItems[k] = v
+	item := &hashMapValue{
+		mapKey:    hashMapKey(key),
+		key:       key,
+		value:     value,
+		insertion: m.nextInsertion,
 	}
-
-	hashMapKey := key.Type() + key.String()
-	mapItems[hashMapKey] = &hashMapValue{
-		key:   key,
-		value: value,
+	nextInsertion := m.nextInsertion + 1
+	// Updating an existing key doesn't change its position in an insertion
+	// ordered map
+	if m.ordered {
+		if existing, ok := m.items.Get(item.mapKey); ok {
+			item.insertion = existing.insertion
+			nextInsertion = m.nextInsertion
+		}
 	}
 
 	return &SketchHashMap{
-		Items: mapItems,
+		items:         m.items.Set(item),
+		ordered:       m.ordered,
+		nextInsertion: nextInsertion,
 	}
 }
 
@@ -107,8 +146,7 @@ func (m *SketchHashMap) Get(key SketchType) (SketchType, error) {
 		return nil, err
 	}
 
-	hashMapKey := key.Type() + key.String()
-	val, ok := m.Items[hashMapKey]
+	val, ok := m.items.Get(hashMapKey(key))
 	if !ok {
 		return nil, fmt.Errorf("map doesn't contain key %s", key)
 	}
@@ -116,22 +154,62 @@ func (m *SketchHashMap) Get(key SketchType) (SketchType, error) {
 	return val.value, nil
 }
 
+// Delete returns a new map, without `key`. If the map doesn't contain `key`,
+// the map is returned unchanged.
+func (m *SketchHashMap) Delete(key SketchType) (*SketchHashMap, error) {
+	if err := ValidHashMapKey(key); err != nil {
+		return nil, err
+	}
+
+	return &SketchHashMap{
+		items:         m.items.Delete(hashMapKey(key)),
+		ordered:       m.ordered,
+		nextInsertion: m.nextInsertion,
+	}, nil
+}
+
+// Len returns the number of items in the map
+func (m *SketchHashMap) Len() int {
+	return m.items.Len()
+}
+
+// Keys returns the map's keys, in the map's iteration order
 func (m *SketchHashMap) Keys() (keys []SketchType) {
-	for _, value := range m.Items {
-		keys = append(keys, value.key)
+	for _, item := range m.sortedItems() {
+		keys = append(keys, item.key)
 	}
 	return keys
 }
 
+// Values returns the map's values, in the map's iteration order
 func (m *SketchHashMap) Values() (values []SketchType) {
-	for _, value := range m.Items {
-		values = append(values, value.value)
+	for _, item := range m.sortedItems() {
+		values = append(values, item.value)
 	}
 	return values
 }
 
+// sortedItems returns the map's items, in the map's iteration order
+func (m *SketchHashMap) sortedItems() []*hashMapValue {
+	items := make([]*hashMapValue, 0, m.items.Len())
+	m.items.Each(func(item *hashMapValue) {
+		items = append(items, item)
+	})
+	if m.ordered {
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].insertion < items[j].insertion
+		})
+	} else {
+		sort.Slice(items, func(i, j int) bool {
+			return Compare(items[i].key, items[j].key) < 0
+		})
+	}
+	return items
+}
+
 type SketchInt struct {
 	Value int
+	Pos   Position
 }
 
 func (i *SketchInt) String() string {
@@ -144,6 +222,7 @@ func (i *SketchInt) Type() string {
 
 type SketchSymbol struct {
 	Value string
+	Pos   Position
 }
 
 func (s *SketchSymbol) String() string {
@@ -155,7 +234,11 @@ func (s *SketchSymbol) Type() string {
 }
 
 type SketchFunction struct {
-	Func              func(args ...SketchType) (SketchType, error)
+	Func func(args ...SketchType) (SketchType, error)
+	// FuncContext is set for functions which need the context of the
+	// evaluation calling them, such as functions defined in Sketch, and
+	// builtins which call other functions. It's used instead of Func by Call
+	FuncContext       func(ctx context.Context, args ...SketchType) (SketchType, error)
 	TailCallOptimised bool
 	AST               SketchType
 	Params            []*SketchSymbol
@@ -165,10 +248,21 @@ type SketchFunction struct {
 	// Functions themselves aren't really named. In Sketch, a function object
 	// is bound to a symbol, which is how you refer to that function.
 	// This variable specifies what the function's bound name is, which is
-	// useful for debugging.
+	// useful for debugging. It's recorded when the function is first bound
+	// with def, defmacro or let, and isn't changed after that, so functions
+	// can be shared between goroutines.
 	BoundName string
 }
 
+// Call calls the function with `args`. `ctx` is the context of the
+// evaluation making the call, which carries its limits and cancellation.
+func (f *SketchFunction) Call(ctx context.Context, args ...SketchType) (SketchType, error) {
+	if f.FuncContext != nil {
+		return f.FuncContext(ctx, args...)
+	}
+	return f.Func(args...)
+}
+
 func (f *SketchFunction) String() string {
 	return "#<function>"
 }
@@ -179,6 +273,7 @@ func (f *SketchFunction) Type() string {
 
 type SketchBoolean struct {
 	Value bool
+	Pos   Position
 }
 
 func (b *SketchBoolean) String() string {
@@ -192,7 +287,9 @@ func (b *SketchBoolean) Type() string {
 	return "boolean"
 }
 
-type SketchNil struct{}
+type SketchNil struct {
+	Pos Position
+}
 
 func (n *SketchNil) String() string {
 	return "nil"
@@ -204,10 +301,13 @@ func (n *SketchNil) Type() string {
 
 type SketchString struct {
 	Value string
+	Pos   Position
 }
 
+// String returns the string as a string literal, escaping any characters
+// which can't appear in one unescaped, so it can be read back by the reader.
 func (s *SketchString) String() string {
-	return fmt.Sprintf(`"%s"`, s.Value)
+	return QuoteString(s.Value)
 }
 
 func (s *SketchString) Type() string {
@@ -217,6 +317,7 @@ func (s *SketchString) Type() string {
 // SketchComment represents a comment in source code.
 type SketchComment struct {
 	Value string
+	Pos   Position
 }
 
 func (c *SketchComment) String() string {
@@ -246,3 +347,86 @@ func (m *SketchModule) String() string {
 func (m *SketchModule) Type() string {
 	return "module"
 }
+
+// SketchException represents an error which has been caught by a `try` form.
+// It lets Sketch code inspect the error, or rethrow it with `throw`.
+type SketchException struct {
+	Message string
+	// Payload is the value passed to `error` or `throw`. It's nil for errors
+	// raised by builtin functions.
+	Payload SketchType
+	// Stack is the call stack which led to the error
+	Stack *CallStack
+	// Err is the error which was caught
+	Err error
+}
+
+func (e *SketchException) String() string {
+	return fmt.Sprintf("#<exception: %s>", e.Message)
+}
+
+func (e *SketchException) Type() string {
+	return "exception"
+}
+
+// SketchResult represents the outcome of an operation which can fail. It's
+// either ok, in which case Value is the result of the operation, or an error,
+// in which case Value describes what went wrong. Results are Sketch's
+// non-exception based way of handling errors.
+type SketchResult struct {
+	Ok    bool
+	Value SketchType
+}
+
+func (r *SketchResult) String() string {
+	if r.Ok {
+		return fmt.Sprintf("(ok %s)", r.Value)
+	}
+	return fmt.Sprintf("(err %s)", r.Value)
+}
+
+func (r *SketchResult) Type() string {
+	return "result"
+}
+
+// QuoteString returns `s` as a double quoted string literal. Double quotes,
+// backslashes and control characters are escaped, using escape sequences
+// understood by both Go and JSON. Bytes which aren't valid UTF-8 are escaped
+// as \xHH.
+func QuoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			fmt.Fprintf(&b, `\x%02x`, s[i])
+			i += size
+			continue
+		}
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		i += size
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,264 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Vector implements an immutable, persistent indexed sequence, using a bit
+// partitioned trie.
+//
+// Items are stored in the leaves of a tree where each node has up to 32
+// children, and each level of the tree is indexed by 5 bits of an item's
+// index. The last (up to) 32 items are kept in a separate tail slice, so
+// appending to a vector usually only copies the tail. Updates copy the path
+// from the root to the changed leaf, and share everything else with the
+// original vector, so Nth, Assoc and Conj are all O(log32 n).
+//
+// Rest returns a view of the same trie which starts one item later, so
+// walking a vector with first and rest is O(n log32 n), rather than copying
+// the vector at each step. The view keeps the items before its start alive.
+type Vector struct {
+	// count is the number of items in the trie and tail, including the
+	// items before start
+	count int
+	// start is the index in the trie of the vector's first item
+	start int
+	// shift is the number of bits the index is shifted by to find the
+	// position in the root node. It's 5 * the depth of the tree
+	shift uint
+	root  *vectorNode
+	tail  []SketchType
+}
+
+const (
+	vectorBitsPerLevel = 5
+	vectorWidth        = 1 << vectorBitsPerLevel
+	vectorMask         = vectorWidth - 1
+)
+
+// vectorNode is a node in a vector's trie. Internal nodes have children, and
+// leaf nodes have items.
+type vectorNode struct {
+	children []*vectorNode
+	items    []SketchType
+}
+
+// NewEmptyVector returns a new empty vector.
+func NewEmptyVector() *Vector {
+	return &Vector{
+		shift: vectorBitsPerLevel,
+		root:  &vectorNode{},
+	}
+}
+
+// NewVector creates a new vector, containing the specified items, in order.
+func NewVector(items []SketchType) *Vector {
+	v := NewEmptyVector()
+	for _, item := range items {
+		v = v.Conj(item)
+	}
+	return v
+}
+
+// Len returns the number of items in the vector
+func (v *Vector) Len() int {
+	return v.count - v.start
+}
+
+// Empty returns whether the vector is empty
+func (v *Vector) Empty() bool {
+	return v.Len() == 0
+}
+
+// Rest returns a vector containing every item but the first. It shares the
+// original vector's trie, so it's O(1).
+func (v *Vector) Rest() *Vector {
+	if v.Len() <= 1 {
+		return NewEmptyVector()
+	}
+	rest := *v
+	rest.start++
+	return &rest
+}
+
+// tailOffset returns the index of the first item in the tail
+func (v *Vector) tailOffset() int {
+	if v.count < vectorWidth {
+		return 0
+	}
+	return ((v.count - 1) >> vectorBitsPerLevel) << vectorBitsPerLevel
+}
+
+// leafFor returns the slice of up to 32 items containing the item at index i.
+// The caller must check that i is in range.
+func (v *Vector) leafFor(i int) []SketchType {
+	if i >= v.tailOffset() {
+		return v.tail
+	}
+	node := v.root
+	for level := v.shift; level > 0; level -= vectorBitsPerLevel {
+		node = node.children[(i>>level)&vectorMask]
+	}
+	return node.items
+}
+
+// Nth returns the item at index i. The second return value is false if i is
+// out of range.
+func (v *Vector) Nth(i int) (SketchType, bool) {
+	if i < 0 || i >= v.Len() {
+		return nil, false
+	}
+	i += v.start
+	return v.leafFor(i)[i&vectorMask], true
+}
+
+// Conj returns a new vector, with `item` appended to it
+func (v *Vector) Conj(item SketchType) *Vector {
+	// There's room in the tail
+	if v.count-v.tailOffset() < vectorWidth {
+		tail := make([]SketchType, len(v.tail), len(v.tail)+1)
+		copy(tail, v.tail)
+		return &Vector{
+			count: v.count + 1,
+			start: v.start,
+			shift: v.shift,
+			root:  v.root,
+			tail:  append(tail, item),
+		}
+	}
+
+	// The tail is full, so we push it into the tree and start a new one
+	tailNode := &vectorNode{items: v.tail}
+	root := v.root
+	shift := v.shift
+	if (v.count >> vectorBitsPerLevel) > (1 << v.shift) {
+		// The tree is full, so we add a new level above the root
+		root = &vectorNode{
+			children: []*vectorNode{v.root, newVectorPath(v.shift, tailNode)},
+		}
+		shift += vectorBitsPerLevel
+	} else {
+		root = v.pushTail(v.shift, v.root, tailNode)
+	}
+
+	return &Vector{
+		count: v.count + 1,
+		start: v.start,
+		shift: shift,
+		root:  root,
+		tail:  []SketchType{item},
+	}
+}
+
+// pushTail returns a copy of `parent`, which is at `level`, with `tailNode`
+// added as the rightmost leaf.
+func (v *Vector) pushTail(level uint, parent *vectorNode, tailNode *vectorNode) *vectorNode {
+	subIndex := ((v.count - 1) >> level) & vectorMask
+	children := make([]*vectorNode, len(parent.children), len(parent.children)+1)
+	copy(children, parent.children)
+
+	var child *vectorNode
+	switch {
+	case level == vectorBitsPerLevel:
+		child = tailNode
+	case subIndex < len(children):
+		child = v.pushTail(level-vectorBitsPerLevel, children[subIndex], tailNode)
+	default:
+		child = newVectorPath(level-vectorBitsPerLevel, tailNode)
+	}
+
+	if subIndex < len(children) {
+		children[subIndex] = child
+	} else {
+		children = append(children, child)
+	}
+	return &vectorNode{children: children}
+}
+
+// newVectorPath returns a chain of nodes from `level` down to `node`
+func newVectorPath(level uint, node *vectorNode) *vectorNode {
+	if level == 0 {
+		return node
+	}
+	return &vectorNode{
+		children: []*vectorNode{newVectorPath(level-vectorBitsPerLevel, node)},
+	}
+}
+
+// Assoc returns a new vector, with the item at index i replaced with `item`.
+// If i is equal to the vector's length, `item` is appended to it.
+func (v *Vector) Assoc(i int, item SketchType) (*Vector, error) {
+	if i == v.Len() {
+		return v.Conj(item), nil
+	}
+	if i < 0 || i > v.Len() {
+		return nil, fmt.Errorf("index out of range - %d, with length %d", i, v.Len())
+	}
+	i += v.start
+
+	if i >= v.tailOffset() {
+		tail := make([]SketchType, len(v.tail))
+		copy(tail, v.tail)
+		tail[i&vectorMask] = item
+		return &Vector{
+			count: v.count,
+			start: v.start,
+			shift: v.shift,
+			root:  v.root,
+			tail:  tail,
+		}, nil
+	}
+
+	return &Vector{
+		count: v.count,
+		start: v.start,
+		shift: v.shift,
+		root:  assocVectorNode(v.shift, v.root, i, item),
+		tail:  v.tail,
+	}, nil
+}
+
+func assocVectorNode(level uint, node *vectorNode, i int, item SketchType) *vectorNode {
+	if level == 0 {
+		items := make([]SketchType, len(node.items))
+		copy(items, node.items)
+		items[i&vectorMask] = item
+		return &vectorNode{items: items}
+	}
+
+	children := make([]*vectorNode, len(node.children))
+	copy(children, node.children)
+	subIndex := (i >> level) & vectorMask
+	children[subIndex] = assocVectorNode(level-vectorBitsPerLevel, children[subIndex], i, item)
+	return &vectorNode{children: children}
+}
+
+// ToSlice returns the items in the vector as a Golang slice.
+func (v *Vector) ToSlice() []SketchType {
+	items := make([]SketchType, 0, v.count)
+	for i := 0; i < v.count; i += vectorWidth {
+		items = append(items, v.leafFor(i)...)
+	}
+	return items[v.start:]
+}
+
+// SketchVector is a vector, written as [1 2 3]. Unlike lists, items can be
+// looked up and updated by index in O(log32 n) time.
+type SketchVector struct {
+	Vector *Vector
+	Pos    Position
+}
+
+func (v *SketchVector) String() string {
+	items := v.Vector.ToSlice()
+	itemStrings := make([]string, len(items))
+	for i, item := range items {
+		itemStrings[i] = item.String()
+	}
+	return fmt.Sprintf("[%s]", strings.Join(itemStrings, " "))
+}
+
+func (v *SketchVector) Type() string {
+	return "vector"
+}
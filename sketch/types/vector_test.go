@@ -0,0 +1,123 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVector(t *testing.T) {
+	// Use enough items that the trie has several levels
+	const n = 40000
+	v := NewEmptyVector()
+	for i := 0; i < n; i++ {
+		v = v.Conj(&SketchInt{Value: i})
+	}
+	require.Equal(t, n, v.Len())
+
+	for i := 0; i < n; i++ {
+		item, ok := v.Nth(i)
+		require.True(t, ok)
+		require.Equal(t, i, item.(*SketchInt).Value)
+	}
+	_, ok := v.Nth(n)
+	assert.False(t, ok)
+	_, ok = v.Nth(-1)
+	assert.False(t, ok)
+
+	items := v.ToSlice()
+	require.Len(t, items, n)
+	for i, item := range items {
+		require.Equal(t, i, item.(*SketchInt).Value)
+	}
+}
+
+func TestVector_Assoc(t *testing.T) {
+	const n = 2000
+	original := NewVector(nil)
+	for i := 0; i < n; i++ {
+		original = original.Conj(&SketchInt{Value: i})
+	}
+
+	updated := original
+	for i := 0; i < n; i += 7 {
+		var err error
+		updated, err = updated.Assoc(i, &SketchInt{Value: -i})
+		require.NoError(t, err)
+	}
+
+	for i := 0; i < n; i++ {
+		item, _ := original.Nth(i)
+		assert.Equal(t, i, item.(*SketchInt).Value, "original vector was modified")
+
+		expected := i
+		if i%7 == 0 {
+			expected = -i
+		}
+		item, _ = updated.Nth(i)
+		assert.Equal(t, expected, item.(*SketchInt).Value)
+	}
+
+	appended, err := original.Assoc(n, &SketchInt{Value: n})
+	require.NoError(t, err)
+	assert.Equal(t, n+1, appended.Len())
+
+	_, err = original.Assoc(n+1, &SketchInt{Value: 0})
+	assert.Error(t, err)
+}
+
+func TestVector_Rest(t *testing.T) {
+	// Use enough items that the trie has several levels
+	const n = 2000
+	items := make([]SketchType, n)
+	for i := range items {
+		items[i] = &SketchInt{Value: i}
+	}
+	v := NewVector(items)
+
+	for i := 0; i < n; i++ {
+		require.Equal(t, n-i, v.Len())
+		first, ok := v.Nth(0)
+		require.True(t, ok)
+		require.Equal(t, i, first.(*SketchInt).Value)
+		v = v.Rest()
+	}
+	assert.True(t, v.Empty())
+	assert.True(t, v.Rest().Empty())
+
+	// Vectors returned by Rest can be updated like any other vector
+	rest := NewVector(items[:40]).Rest()
+	assert.Equal(t, items[1:40], rest.ToSlice())
+	_, ok := rest.Nth(39)
+	assert.False(t, ok)
+
+	updated, err := rest.Assoc(0, &SketchInt{Value: -1})
+	require.NoError(t, err)
+	first, _ := updated.Nth(0)
+	assert.Equal(t, -1, first.(*SketchInt).Value)
+	original, _ := rest.Nth(0)
+	assert.Equal(t, 1, original.(*SketchInt).Value)
+
+	conjed := rest.Conj(&SketchInt{Value: 40})
+	assert.Equal(t, 40, conjed.Len())
+	last, _ := conjed.Nth(39)
+	assert.Equal(t, 40, last.(*SketchInt).Value)
+}
+
+// BenchmarkVector_FirstRest10k walks a 10,000 item vector with Nth(0) and
+// Rest, as Sketch code does with first and rest.
+func BenchmarkVector_FirstRest10k(b *testing.B) {
+	items := make([]SketchType, 10000)
+	for i := range items {
+		items[i] = &SketchInt{Value: i}
+	}
+	v := NewVector(items)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for rest := v; !rest.Empty(); rest = rest.Rest() {
+			rest.Nth(0)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+)
+
+var (
+	errorType      = reflect.TypeOf((*error)(nil)).Elem()
+	sketchTypeType = reflect.TypeOf((*types.SketchType)(nil)).Elem()
+)
+
+// WrapFunc turns the Go function `f` into a Sketch function called `fnName`.
+// The Sketch function converts its arguments to the types of `f`'s parameters
+// with types.ToGo, calls `f`, and converts its result back with types.FromGo.
+// If it's called with the wrong number of arguments, or with arguments which
+// can't be converted, it returns an error, like the builtins do.
+//
+// `f` can be variadic. It can return nothing, a value, an error, or a value
+// and an error. For example:
+//
+//	validation.WrapFunc("starts-with?", func(s string, prefix string) bool {
+//		return strings.HasPrefix(s, prefix)
+//	})
+func WrapFunc(fnName string, f interface{}) (*types.SketchFunction, error) {
+	fn := reflect.ValueOf(f)
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func {
+		return nil, fmt.Errorf("WrapFunc: %s must be a function, got %s", fnName, fnType)
+	}
+	if err := validateResults(fnName, fnType); err != nil {
+		return nil, err
+	}
+
+	call := func(args ...types.SketchType) (types.SketchType, error) {
+		numParams := fnType.NumIn()
+		if fnType.IsVariadic() {
+			if err := MinArgs(fnName, numParams-1, args); err != nil {
+				return nil, err
+			}
+		} else if err := NArgs(fnName, numParams, args); err != nil {
+			return nil, err
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := variadicParamType(fnType, i)
+			param := reflect.New(paramType)
+			if err := types.ToGo(arg, param.Interface()); err != nil {
+				return nil, argConversionError(fnName, arg, paramType, i, err)
+			}
+			in[i] = param.Elem()
+		}
+
+		return convertResults(fn.Call(in))
+	}
+
+	return &types.SketchFunction{
+		Func:      RejectErrorArgs(fnName, call),
+		BoundName: fnName,
+	}, nil
+}
+
+// validateResults checks that a function wrapped by WrapFunc returns at most
+// one value, optionally followed by an error.
+func validateResults(fnName string, fnType reflect.Type) error {
+	numResults := fnType.NumOut()
+	if numResults > 0 && fnType.Out(numResults-1) == errorType {
+		numResults--
+	}
+	if numResults > 1 {
+		return fmt.Errorf("WrapFunc: %s must return at most one value and an error, got %s", fnName, fnType)
+	}
+	return nil
+}
+
+// variadicParamType returns the type of the parameter the `i`th argument is
+// passed as.
+func variadicParamType(fnType reflect.Type, i int) reflect.Type {
+	last := fnType.NumIn() - 1
+	if fnType.IsVariadic() && i >= last {
+		return fnType.In(last).Elem()
+	}
+	return fnType.In(i)
+}
+
+// convertResults converts the values returned by a function wrapped by
+// WrapFunc to a Sketch value. Functions which return nothing return nil.
+func convertResults(results []reflect.Value) (types.SketchType, error) {
+	if n := len(results); n > 0 && results[n-1].Type() == errorType {
+		if err, _ := results[n-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		results = results[:n-1]
+	}
+	if len(results) == 0 {
+		return &types.SketchNil{}, nil
+	}
+	return types.FromGo(results[0].Interface())
+}
+
+func argConversionError(
+	fnName string, arg types.SketchType, paramType reflect.Type, position int, err error,
+) error {
+	expectedType := sketchTypeName(paramType)
+	if expectedType == arg.Type() {
+		// The argument has the right type, but its value doesn't fit, e.g. an
+		// int which overflows an int8
+		return fmt.Errorf(
+			"the function %s was passed an invalid %s argument: %w",
+			fnName, ToOrdinal(position+1), err)
+	}
+	return fmt.Errorf(
+		"the function %s expects the %s argument `%s` to be type %s, got type %s",
+		fnName, ToOrdinal(position+1), arg, expectedType, arg.Type())
+}
+
+// sketchTypeName returns the name of the Sketch type which is converted to
+// the Go type `t`.
+func sketchTypeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr && t.Implements(sketchTypeType) {
+		return reflect.New(t.Elem()).Interface().(types.SketchType).Type()
+	}
+
+	switch t {
+	case reflect.TypeOf((*big.Int)(nil)):
+		return "int"
+	case reflect.TypeOf((*big.Rat)(nil)):
+		return "ratio"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "list or vector"
+	case reflect.Map, reflect.Struct:
+		return "hashmap"
+	case reflect.Ptr:
+		return sketchTypeName(t.Elem())
+	}
+	return t.String()
+}
@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapFunc(t *testing.T) {
+	repeat, err := WrapFunc("repeat", func(s string, n int) (string, error) {
+		if n < 0 {
+			return "", errors.New("n must not be negative")
+		}
+		return strings.Repeat(s, n), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "repeat", repeat.BoundName)
+
+	result, err := repeat.Func(&types.SketchString{Value: "ab"}, &types.SketchInt{Value: 2})
+	require.NoError(t, err)
+	assert.Equal(t, &types.SketchString{Value: "abab"}, result)
+
+	_, err = repeat.Func(&types.SketchString{Value: "ab"})
+	assert.EqualError(t, err, "the function repeat expects 2 arguments, but got 1")
+
+	_, err = repeat.Func(&types.SketchInt{Value: 1}, &types.SketchInt{Value: 2})
+	assert.EqualError(t, err, "the function repeat expects the 1st argument `1` to be type string, got type int")
+
+	_, err = repeat.Func(&types.SketchString{Value: "ab"}, &types.SketchInt{Value: -1})
+	assert.EqualError(t, err, "n must not be negative")
+
+	_, err = repeat.Func(&types.SketchString{Value: "ab"}, &types.SketchResult{Ok: false, Value: &types.SketchString{Value: "err"}})
+	assert.Error(t, err)
+}
+
+func TestWrapFunc_Variadic(t *testing.T) {
+	sum, err := WrapFunc("sum", func(first float64, rest ...float64) float64 {
+		for _, n := range rest {
+			first += n
+		}
+		return first
+	})
+	require.NoError(t, err)
+
+	result, err := sum.Func(&types.SketchInt{Value: 1}, &types.SketchFloat{Value: 0.5}, &types.SketchInt{Value: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "3.5", result.String())
+
+	_, err = sum.Func()
+	assert.EqualError(t, err, "the function sum expects at least 1 arguments, but got 0")
+
+	_, err = sum.Func(&types.SketchInt{Value: 1}, &types.SketchString{Value: "a"})
+	assert.EqualError(t, err, "the function sum expects the 2nd argument `\"a\"` to be type number, got type string")
+}
+
+func TestWrapFunc_Results(t *testing.T) {
+	called := false
+	noResults, err := WrapFunc("no-results", func() { called = true })
+	require.NoError(t, err)
+	result, err := noResults.Func()
+	require.NoError(t, err)
+	assert.Equal(t, "nil", result.String())
+	assert.True(t, called)
+
+	_, err = WrapFunc("two-results", func() (int, int) { return 1, 2 })
+	assert.Error(t, err)
+
+	_, err = WrapFunc("not-a-function", 1)
+	assert.Error(t, err)
+}
+
+func TestWrapFunc_InvalidValue(t *testing.T) {
+	f, err := WrapFunc("small", func(n int8) int8 { return n })
+	require.NoError(t, err)
+
+	_, err = f.Func(&types.SketchInt{Value: 1000})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the function small was passed an invalid 1st argument")
+}
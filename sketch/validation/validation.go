@@ -13,6 +13,14 @@ func NArgs(fnName string, n int, args []types.SketchType) error {
 	return nil
 }
 
+// MinArgs validates that at least `n` arguments were supplied.
+func MinArgs(fnName string, n int, args []types.SketchType) error {
+	if numArgs := len(args); numArgs < n {
+		return fmt.Errorf("the function %s expects at least %d arguments, but got %d", fnName, n, numArgs)
+	}
+	return nil
+}
+
 func NArgsRange(fnName string, lower, upper int, args []types.SketchType) error {
 	numArgs := len(args)
 	if numArgs < lower || numArgs > upper {
@@ -35,6 +43,34 @@ func NIntArgs(fnName string, n int, args []types.SketchType) ([]*types.SketchInt
 	return numbers, nil
 }
 
+// NNumberArgs validates that exactly `n` arguments were supplied, and that
+// they're all numbers.
+func NNumberArgs(fnName string, n int, args []types.SketchType) ([]types.SketchType, error) {
+	if err := NArgs(fnName, n, args); err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		if _, err := NumberArg(fnName, arg, i); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// MinNumberArgs validates that at least `n` arguments were supplied, and that
+// they're all numbers.
+func MinNumberArgs(fnName string, n int, args []types.SketchType) ([]types.SketchType, error) {
+	if err := MinArgs(fnName, n, args); err != nil {
+		return nil, err
+	}
+	for i, arg := range args {
+		if _, err := NumberArg(fnName, arg, i); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
 func ListArg(
 	fnName string, arg types.SketchType, position int,
 ) (*types.SketchList, error) {
@@ -44,6 +80,39 @@ func ListArg(
 	return arg.(*types.SketchList), nil
 }
 
+// SequenceArg validates that `arg` is a list or a vector, and returns its
+// items.
+func SequenceArg(
+	fnName string, arg types.SketchType, position int,
+) ([]types.SketchType, error) {
+	items, ok := types.SequenceItems(arg)
+	if !ok {
+		oneIndexedPosition := position + 1
+		return nil, fmt.Errorf(
+			"the function %s expects the %s argument `%s` to be a list or vector, got type %s",
+			fnName, ToOrdinal(oneIndexedPosition), arg, arg.Type())
+	}
+	return items, nil
+}
+
+func VectorArg(
+	fnName string, arg types.SketchType, position int,
+) (*types.SketchVector, error) {
+	if err := ArgType(fnName, arg, "vector", position); err != nil {
+		return nil, err
+	}
+	return arg.(*types.SketchVector), nil
+}
+
+func SetArg(
+	fnName string, arg types.SketchType, position int,
+) (*types.SketchSet, error) {
+	if err := ArgType(fnName, arg, "set", position); err != nil {
+		return nil, err
+	}
+	return arg.(*types.SketchSet), nil
+}
+
 func IntArg(
 	fnName string, arg types.SketchType, position int,
 ) (*types.SketchInt, error) {
@@ -53,6 +122,19 @@ func IntArg(
 	return arg.(*types.SketchInt), nil
 }
 
+// NumberArg validates that `arg` is one of Sketch's numeric types.
+func NumberArg(
+	fnName string, arg types.SketchType, position int,
+) (types.SketchType, error) {
+	if !types.IsNumber(arg) {
+		oneIndexedPosition := position + 1
+		return nil, fmt.Errorf(
+			"the function %s expects the %s argument `%s` to be a number, got type %s",
+			fnName, ToOrdinal(oneIndexedPosition), arg, arg.Type())
+	}
+	return arg, nil
+}
+
 func StringArg(
 	fnName string, arg types.SketchType, position int,
 ) (*types.SketchString, error) {
@@ -62,9 +144,15 @@ func StringArg(
 	return arg.(*types.SketchString), nil
 }
 
+// FunctionArg validates that `arg` is a function. Keywords can be called like
+// functions, so they're accepted too, and converted to a function with
+// SketchKeyword.Function.
 func FunctionArg(
 	fnName string, arg types.SketchType, position int,
 ) (*types.SketchFunction, error) {
+	if keyword, ok := arg.(*types.SketchKeyword); ok {
+		return keyword.Function(), nil
+	}
 	if err := ArgType(fnName, arg, "function", position); err != nil {
 		return nil, err
 	}
@@ -89,6 +177,54 @@ func HashMapArg(
 	return arg.(*types.SketchHashMap), nil
 }
 
+func ExceptionArg(
+	fnName string, arg types.SketchType, position int,
+) (*types.SketchException, error) {
+	if err := ArgType(fnName, arg, "exception", position); err != nil {
+		return nil, err
+	}
+	return arg.(*types.SketchException), nil
+}
+
+func ResultArg(
+	fnName string, arg types.SketchType, position int,
+) (*types.SketchResult, error) {
+	if err := ArgType(fnName, arg, "result", position); err != nil {
+		return nil, err
+	}
+	return arg.(*types.SketchResult), nil
+}
+
+// NoErrorArgs returns an error if any of `args` is an error result. Functions
+// which don't explicitly handle results call this, so that an error result
+// which hasn't been handled is reported where it's first used.
+func NoErrorArgs(fnName string, args []types.SketchType) error {
+	for i, arg := range args {
+		result, ok := arg.(*types.SketchResult)
+		if !ok || result.Ok {
+			continue
+		}
+		return fmt.Errorf(
+			"the function %s was passed the unhandled error value %s as its %s argument - handle it with ok?, unwrap or unwrap-or first",
+			fnName, result, ToOrdinal(i+1))
+	}
+	return nil
+}
+
+// RejectErrorArgs wraps the builtin function `f`, so it returns an error if
+// it's called with an error result. Builtins are registered with this unless
+// they explicitly handle results.
+func RejectErrorArgs(
+	fnName string, f func(...types.SketchType) (types.SketchType, error),
+) func(...types.SketchType) (types.SketchType, error) {
+	return func(args ...types.SketchType) (types.SketchType, error) {
+		if err := NoErrorArgs(fnName, args); err != nil {
+			return nil, err
+		}
+		return f(args...)
+	}
+}
+
 func ArgType(
 	fnName string, arg types.SketchType, expectedType string, position int,
 ) error {
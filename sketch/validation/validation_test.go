@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jamesroutley/sketch/sketch/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -50,3 +51,13 @@ func TestToOrdinal(t *testing.T) {
 		})
 	}
 }
+
+func TestNoErrorArgs(t *testing.T) {
+	okResult := &types.SketchResult{Ok: true, Value: &types.SketchInt{Value: 1}}
+	errResult := &types.SketchResult{Ok: false, Value: &types.SketchString{Value: "oh no"}}
+
+	assert.NoError(t, NoErrorArgs("f", []types.SketchType{okResult, &types.SketchInt{Value: 1}}))
+
+	err := NoErrorArgs("f", []types.SketchType{okResult, errResult})
+	assert.EqualError(t, err, `the function f was passed the unhandled error value (err "oh no") as its 2nd argument - handle it with ok?, unwrap or unwrap-or first`)
+}
@@ -0,0 +1,128 @@
+package sketchtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch"
+	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/evaluator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// These tests exercise pmap and pfilter, which call functions in parallel.
+// They should be run with -race.
+func TestParallelMapAndFilter(t *testing.T) {
+	runTests(t, []*TestCase{
+		{
+			name: "map over a closure which calls named functions",
+			input: `
+(do
+	(def square (fn (x) (* x x)))
+	(def offset 10)
+	(let ((add-offset (fn (x) (+ (square x) offset))))
+		(pmap (fn (x) (add-offset x)) (range 20))))`,
+			expected: "(10 11 14 19 26 35 46 59 74 91 110 131 154 179 206 235 266 299 334 371)",
+		},
+		{
+			name: "map over a closure which defines symbols",
+			input: `
+(do
+	(def f (fn (x) (do (def y (* x 2)) (+ x y))))
+	(pmap f (range 10)))`,
+			expected: "(0 3 6 9 12 15 18 21 24 27)",
+		},
+		{
+			name: "nested map and filter",
+			input: `
+(do
+	(def even? (fn (x) (= (modulo x 2) 0)))
+	(pmap (fn (xs) (pfilter even? xs)) (list (range 6) (range 10))))`,
+			expected: "((0 2 4) (0 2 4 6 8))",
+		},
+		{
+			name:     "pmap over a vector returns a vector",
+			input:    `(pmap (fn (x) (* x 2)) [1 2 3])`,
+			expected: "[2 4 6]",
+		},
+		{
+			name:     "pfilter over a vector returns a vector",
+			input:    `(pfilter (fn (x) (> x 1)) [1 2 3])`,
+			expected: "[2 3]",
+		},
+		{
+			name:     "pmap reports the error for the lowest failing index",
+			input:    `(try (pmap (fn (x) (if (> x 3) (error "failed" x) x)) (range 200)) (catch e (exception-payload e)))`,
+			expected: "4",
+		},
+		{
+			name:     "pfilter reports the error for the lowest failing index",
+			input:    `(try (pfilter (fn (x) (if (> x 6) (error "failed" x) true)) (range 200)) (catch e (exception-payload e)))`,
+			expected: "7",
+		},
+		{
+			name:     "with-parallelism",
+			input:    `(with-parallelism 2 (pmap (fn (x) (+ x 1)) (list 1 2 3)))`,
+			expected: "(2 3 4)",
+		},
+		{
+			name:          "with-parallelism must be positive",
+			input:         `(with-parallelism 0 (pmap (fn (x) x) (list 1)))`,
+			expectedError: fmt.Errorf("with-parallelism: the parallelism must be at least 1, got 0"),
+		},
+		{
+			name:          "with-parallelism takes an int",
+			input:         `(with-parallelism "2" (pmap (fn (x) x) (list 1)))`,
+			expectedError: fmt.Errorf("the function with-parallelism expects the 1st argument to be type int"),
+		},
+	})
+}
+
+func TestConcurrentEvaluation(t *testing.T) {
+	env, err := evaluator.RootEnvironment()
+	require.NoError(t, err)
+	_, err = sketch.Rep(`(def f (fn (x) (+ x 1)))`, env)
+	require.NoError(t, err)
+
+	// Goroutines share the root environment, reading f and defining their
+	// own symbols in it
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, err := sketch.Rep(fmt.Sprintf(`(def x%d (f %d))`, i, i), env)
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprint(i+1), result)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		result, err := sketch.Rep(fmt.Sprintf(`x%d`, i), env)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprint(i+1), result)
+	}
+}
+
+func TestFunctionNamesAreRecordedByDef(t *testing.T) {
+	env, err := evaluator.RootEnvironment()
+	require.NoError(t, err)
+
+	// Looking a function up by another name doesn't rename it
+	_, err = sketch.Rep(`(do
+	(def f (fn (x) (nth x 5)))
+	(def g f)
+	(g (list 1)))`, env)
+	require.Error(t, err)
+
+	var xerr *errors.Error
+	require.ErrorAs(t, err, &xerr)
+	var names []string
+	for _, frame := range xerr.Stack.Frames() {
+		names = append(names, frame.FunctionName)
+	}
+	assert.Equal(t, []string{"f", "nth"}, names)
+}
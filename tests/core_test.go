@@ -23,6 +23,22 @@ func TestStringToList(t *testing.T) {
 	runTests(t, cases)
 }
 
+func TestEquals(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "functions are equal to themselves",
+			input:    `(do (def f (fn (x) x)) (list (= + +) (= f f) (= + -) (= f (fn (x) x))))`,
+			expected: `(true true false false)`,
+		},
+		{
+			name:     "exceptions are compared by identity",
+			input:    `(let ((e (try (error "a") (catch e e)))) (list (= e e) (= e (try (error "a") (catch e e)))))`,
+			expected: `(true false)`,
+		},
+	}
+	runTests(t, cases)
+}
+
 func TestFilter(t *testing.T) {
 	cases := []*TestCase{
 		{
@@ -110,12 +126,12 @@ func TestCore(t *testing.T) {
 		{
 			name:     "hashset",
 			input:    "(hashset 1 2 3)",
-			expected: "{1 true 2 true 3 true}",
+			expected: "#{1 2 3}",
 		},
 		{
 			name:     "empty hashset",
 			input:    "(hashset)",
-			expected: "{}",
+			expected: "#{}",
 		},
 
 		{
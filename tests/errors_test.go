@@ -0,0 +1,114 @@
+package sketchtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch"
+	"github.com/jamesroutley/sketch/sketch/errors"
+	"github.com/jamesroutley/sketch/sketch/evaluator"
+	"github.com/jamesroutley/sketch/sketch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorPositions(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "undefined symbol",
+			input:    "(do (def a 1) b)",
+			expected: "1:15: `b` is undefined",
+		},
+		{
+			name: "error inside a function reports the innermost form",
+			input: `(do
+	(def f (fn (x) (+ x y)))
+	(f 1))`,
+			expected: "2:22: `y` is undefined",
+		},
+		{
+			name:     "error in builtin reports the call site",
+			input:    "(do\n  (nth (list 1) 5))",
+			expected: "2:3: nth: index out of range - 5, with length 1, [1]",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			env, err := evaluator.RootEnvironment()
+			require.NoError(t, err)
+
+			_, err = sketch.Rep(tc.input, env)
+			require.Error(t, err)
+			assert.Equal(t, tc.expected, err.Error())
+		})
+	}
+}
+
+func TestErrorCallStackPositions(t *testing.T) {
+	env, err := evaluator.RootEnvironment()
+	require.NoError(t, err)
+
+	_, err = sketch.Rep(`(do
+	(def f (fn (x) (nth x 5)))
+	(f (list 1)))`, env)
+	require.Error(t, err)
+
+	xerr, ok := err.(*errors.Error)
+	require.True(t, ok)
+	frames := xerr.Stack.Frames()
+	require.Len(t, frames, 2)
+	assert.Equal(t, "f", frames[0].FunctionName)
+	assert.Equal(t, types.Position{Line: 3, Column: 2}, frames[0].Pos)
+	assert.Equal(t, "nth", frames[1].FunctionName)
+	assert.Equal(t, types.Position{Line: 2, Column: 17}, frames[1].Pos)
+	assert.Equal(t, "((1) 5)", (&types.SketchList{List: types.NewList(frames[1].Args)}).String())
+}
+
+func TestErrorCallStackOrder(t *testing.T) {
+	env, err := evaluator.RootEnvironment()
+	require.NoError(t, err)
+
+	// The error happens while evaluating the arguments to +, so g's frame
+	// is pushed by a nested call to Eval
+	_, err = sketch.Rep(`(do
+	(def g (fn () (nth (list) 1)))
+	(def f (fn () (+ 1 (g))))
+	(f))`, env)
+	require.Error(t, err)
+
+	var xerr *errors.Error
+	require.ErrorAs(t, err, &xerr)
+	var names []string
+	for _, frame := range xerr.Stack.Frames() {
+		names = append(names, frame.FunctionName)
+	}
+	assert.Equal(t, []string{"f", "g", "nth"}, names)
+}
+
+func TestErrorCallStackCollapsesRecursion(t *testing.T) {
+	env, err := evaluator.RootEnvironment()
+	require.NoError(t, err)
+
+	_, err = sketch.Rep(`(do
+	(def f (fn (n) (if (= n 0) (error "bottom") (+ 1 (f (- n 1))))))
+	(f 100))`, env)
+	require.Error(t, err)
+
+	var buf strings.Builder
+	errors.Fprint(&buf, err)
+	assert.Equal(t, `2:29: bottom
+
+Call stack:
+   (f 100) at 3:2
+   (f 99) at 2:51
+   ... f called 100 times
+   (error "bottom") at 2:29
+`, buf.String())
+}
@@ -0,0 +1,64 @@
+package sketchtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jamesroutley/sketch/sketch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExamples runs each example program, and checks it prints the expected
+// answer. Every example must be listed, so changes which break one are
+// caught.
+func TestExamples(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the examples take a few seconds to run")
+	}
+
+	expected := map[string]string{
+		"1a.skt": "181044\n",
+		"2a.skt": "383\n",
+		"3a.skt": "234\n",
+		"4a.skt": "264\n",
+		"5.skt":  "989\n(548)\n",
+		"6a.skt": "6911\n",
+		"7a.skt": "326\n",
+		"8a.skt": "1928\n",
+		"9a.skt": "177777905\n",
+	}
+
+	// The examples read their input relative to the root of the repo. The
+	// subtests aren't parallel, so nothing else runs in the wrong directory
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(".."))
+	defer os.Chdir(wd)
+
+	examples, err := filepath.Glob(filepath.Join("examples", "advent-of-code", "*.skt"))
+	require.NoError(t, err)
+	require.Len(t, examples, len(expected))
+
+	for _, example := range examples {
+		example := example
+		t.Run(filepath.Base(example), func(t *testing.T) {
+			want, ok := expected[filepath.Base(example)]
+			require.True(t, ok, "no expected output for %s", example)
+
+			f, err := os.Open(example)
+			require.NoError(t, err)
+			defer f.Close()
+
+			var stdout bytes.Buffer
+			interpreter, err := sketch.New(sketch.WithStdout(&stdout))
+			require.NoError(t, err)
+
+			_, err = interpreter.EvalReader(f, example)
+			require.NoError(t, err)
+			assert.Equal(t, want, stdout.String())
+		})
+	}
+}
@@ -4,6 +4,26 @@ import "testing"
 
 func TestHashMap(t *testing.T) {
 	cases := []*TestCase{
+		{
+			name:     "equal lists are the same key",
+			input:    "(hashmap-get {(1) 3} (list 1.0))",
+			expected: "3",
+		},
+		{
+			name:     "equal lists and vectors are the same key",
+			input:    "(list (hashmap-get {[1 (2)] 3} (list 1 [2])) (count (hashmap-keys (hashmap [1] 1 (list 1) 2))))",
+			expected: "(3 1)",
+		},
+		{
+			name:     "equal sets are the same key",
+			input:    "(hashmap-get (hashmap #{1 [2]} 3) #{(list 2) 1.0})",
+			expected: "3",
+		},
+		{
+			name:     "lists with different items are different keys",
+			input:    `(count (hashmap-keys (hashmap (list "a" "b") 1 (list "ab") 2 (list (list "a") "b") 3)))`,
+			expected: "3",
+		},
 		{
 			name:     "Hash map literal",
 			input:    "{1 2}",
@@ -41,6 +61,81 @@ func TestHashMap(t *testing.T) {
 			input:    `(hashmap-values {1 2 3 4})`,
 			expected: "(2 4)",
 		},
+		{
+			name:     "Hash map delete",
+			input:    `(hashmap-delete {1 2 3 4} 1)`,
+			expected: "{3 4}",
+		},
+		{
+			name:     "Hash map delete missing key",
+			input:    `(hashmap-delete {1 2} 3)`,
+			expected: "{1 2}",
+		},
+		{
+			name:     "Hash map delete doesn't modify the original map",
+			input:    `(do (def h {1 2}) (hashmap-delete h 1) h)`,
+			expected: "{1 2}",
+		},
+		{
+			name:     "Hash map length",
+			input:    `(length (fold-left (fn (h i) (hashmap-set h i i)) {} (range 1000)))`,
+			expected: "1000",
+		},
+		{
+			name:     "Hash map with many keys",
+			input:    `(hashmap-get (fold-left (fn (h i) (hashmap-set h i (* i i))) {} (range 1000)) 999)`,
+			expected: "998001",
+		},
+		{
+			name:     "Hash maps are printed in sorted order",
+			input:    `{"b" 1 "a" 2 3 3 1 4 false 5 true 6 (1 2) 7 sym 8 (1) 9}`,
+			expected: `{false 5 true 6 1 4 3 3 "a" 2 "b" 1 sym 8 (1) 9 (1 2) 7}`,
+		},
+		{
+			name:     "Numbers of different types are sorted by value",
+			input:    `(hashmap-keys (hashmap 2 nil 1.5 nil 1/2 nil 100000000000000000000 nil -1 nil))`,
+			expected: "(-1 1/2 1.5 2 100000000000000000000)",
+		},
+		{
+			name:     "Hash map order doesn't depend on insertion order",
+			input:    `(= (hashmap-keys (hashmap-set (hashmap-set {} "a" 1) "b" 2)) (hashmap-keys (hashmap-set (hashmap-set {} "b" 2) "a" 1)))`,
+			expected: "true",
+		},
+		{
+			name:     "Hash map equality",
+			input:    `(list (= {1 2} {1 2}) (= {1 2} {1 3}) (= {1 2} {2 2}) (= {1 2} {1 2 3 4}) (= {1 [2]} {1.0 (list 2.0)}) (= {1 2} (list 1 2)))`,
+			expected: "(true false false false true false)",
+		},
+		{
+			name:     "Hash map equality ignores insertion order",
+			input:    `(list (= (ordered-hashmap 1 2 3 4) (ordered-hashmap 3 4 1 2)) (= (ordered-hashmap 1 2) {1 2}))`,
+			expected: "(true true)",
+		},
+		{
+			name:     "Functions in hash maps are compared by identity",
+			input:    `(list (= {1 +} {1 +}) (= {1 +} {1 -}))`,
+			expected: "(true false)",
+		},
+		{
+			name:     "Ordered hash map",
+			input:    `(ordered-hashmap "b" 1 "a" 2 "c" 3)`,
+			expected: `{"b" 1 "a" 2 "c" 3}`,
+		},
+		{
+			name:     "Ordered hash map keeps insertion order",
+			input:    `(hashmap-keys (hashmap-set (hashmap-set (ordered-hashmap 3 nil) 1 nil) 2 nil))`,
+			expected: "(3 1 2)",
+		},
+		{
+			name:     "Updating an ordered hash map key doesn't move it",
+			input:    `(hashmap-set (ordered-hashmap "b" 1 "a" 2) "b" 3)`,
+			expected: `{"b" 3 "a" 2}`,
+		},
+		{
+			name:     "Deleting and re-adding an ordered hash map key moves it to the end",
+			input:    `(hashmap-set (hashmap-delete (ordered-hashmap "b" 1 "a" 2) "b") "b" 3)`,
+			expected: `{"a" 2 "b" 3}`,
+		},
 	}
 	runTests(t, cases)
 }
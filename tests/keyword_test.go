@@ -0,0 +1,107 @@
+package sketchtest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeyword(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "keywords evaluate to themselves",
+			input:    ":name",
+			expected: ":name",
+		},
+		{
+			name:     "keywords in a list",
+			input:    "(list :a :b)",
+			expected: "(:a :b)",
+		},
+		{
+			name:     "keyword equality",
+			input:    `(list (= :a :a) (= :a :b) (= :a "a") (= :a (keyword "a")))`,
+			expected: "(true false false true)",
+		},
+		{
+			name:     "keywords as hash map keys",
+			input:    `(hashmap-get {:name "Sketch" "name" "string"} :name)`,
+			expected: `"Sketch"`,
+		},
+		{
+			name:     "keywords look themselves up in hash maps",
+			input:    `(:name {:name "Sketch"})`,
+			expected: `"Sketch"`,
+		},
+		{
+			name:     "keyword lookup with a default",
+			input:    `(:age {:name "Sketch"} 0)`,
+			expected: "0",
+		},
+		{
+			name:     "keyword lookup in a bound map",
+			input:    `(do (def person {:name "Ada" :age 36}) (+ (:age person) 1))`,
+			expected: "37",
+		},
+		{
+			name:          "keyword lookup of a missing key",
+			input:         `(:age {:name "Sketch"})`,
+			expectedError: errors.New("map doesn't contain key :age"),
+		},
+		{
+			name:          "keyword lookup in a non-map",
+			input:         `(:age (list 1))`,
+			expectedError: errors.New("the function :age expects the 1st argument `(1)` to be type hashmap, got type list"),
+		},
+		{
+			name:     "keywords can be passed to map",
+			input:    `(map :a (list {:a 1} {:a 2}))`,
+			expected: "(1 2)",
+		},
+		{
+			name:     "keywords can be passed to pmap",
+			input:    `(pmap :a [{:a 1} {:a 2}])`,
+			expected: "[1 2]",
+		},
+		{
+			name:     "keywords can be passed to filter",
+			input:    `(filter :ok (list {:ok true :n 1} {:ok false :n 2}))`,
+			expected: "({:n 1 :ok true})",
+		},
+		{
+			name:     "keywords can be passed to apply",
+			input:    `(apply :a (list {:b 1} "missing"))`,
+			expected: `"missing"`,
+		},
+		{
+			name:     "keywords passed to map report lookup errors",
+			input:    `(try (map :a (list {:b 1})) (catch e (exception-message e)))`,
+			expected: `"map doesn't contain key :a"`,
+		},
+		{
+			name:     "keyword",
+			input:    `(list (keyword "a") (keyword (quote b)) (keyword :c))`,
+			expected: "(:a :b :c)",
+		},
+		{
+			name:     "keyword?",
+			input:    `(list (keyword? :a) (keyword? "a") (keyword? (quote a)))`,
+			expected: "(true false false)",
+		},
+		{
+			name:     "name",
+			input:    `(list (name :a) (name (quote b)))`,
+			expected: `("a" "b")`,
+		},
+		{
+			name:     "keywords sort before strings",
+			input:    `{"b" 1 :b 2 :a 3}`,
+			expected: `{:a 3 :b 2 "b" 1}`,
+		},
+		{
+			name:     "cond with :else",
+			input:    `(cond (false 1) (:else 2))`,
+			expected: "2",
+		},
+	}
+	runTests(t, cases)
+}
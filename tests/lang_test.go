@@ -1,6 +1,7 @@
 package sketchtest
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -92,6 +93,27 @@ func TestRecursion(t *testing.T) {
 	runTests(t, cases)
 }
 
+func TestReadForms(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "comment",
+			input:    "; just a comment",
+			expected: "",
+		},
+		{
+			name:     "multiple forms are evaluated in order",
+			input:    "(def a 1) ; comment\n(+ a 1)",
+			expected: "2",
+		},
+		{
+			name:          "syntax error",
+			input:         "(def a 1) (+ a 1",
+			expectedError: errors.New("1:17: unclosed `(` opened at 1:11"),
+		},
+	}
+	runTests(t, cases)
+}
+
 func TestReadString(t *testing.T) {
 	cases := []*TestCase{
 		{
@@ -104,6 +126,24 @@ func TestReadString(t *testing.T) {
 			input:    `"hello \" world"`,
 			expected: `"hello \" world"`,
 		},
+		{
+			name:     "escape sequences are re-escaped when printed",
+			input:    `"a\tb\\c\u00e9\u0001"`,
+			expected: `"a\tb\\cé\u0001"`,
+		},
+		{
+			name:     "pr-str",
+			input:    `(pr-str "a" 1 (list "b\n"))`,
+			expected: `"\"a\" 1 (\"b\\n\")"`,
+		},
+		{
+			name: "read-string reads strings printed by pr-str",
+			input: `
+(do
+	(def s "quote \" backslash \\ newline \n tab \t unicode é \u0000 \xff")
+	(= s (read-string (pr-str s))))`,
+			expected: "true",
+		},
 	}
 	runTests(t, cases)
 }
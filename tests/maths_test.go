@@ -0,0 +1,391 @@
+package sketchtest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFloats(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "float literal",
+			input:    "1.5",
+			expected: "1.5",
+		},
+		{
+			name:     "float literal with exponent",
+			input:    "2.5e3",
+			expected: "2500.0",
+		},
+		{
+			name:     "float literal with negative exponent",
+			input:    "-25E-1",
+			expected: "-2.5",
+		},
+		{
+			name:     "integral floats are printed as floats",
+			input:    "2.0",
+			expected: "2.0",
+		},
+		{
+			name:     "add floats",
+			input:    "(+ 1.5 2.25)",
+			expected: "3.75",
+		},
+		{
+			name:     "add ints and floats",
+			input:    "(+ 1 1.5 2)",
+			expected: "4.5",
+		},
+		{
+			name:     "subtract int and float",
+			input:    "(- 3 0.5)",
+			expected: "2.5",
+		},
+		{
+			name:     "multiply int and float",
+			input:    "(* 2 1.5)",
+			expected: "3.0",
+		},
+		{
+			name:     "dividing a float by an int returns a float",
+			input:    "(/ 7.0 2)",
+			expected: "3.5",
+		},
+		{
+			name:     "exact int division returns an int",
+			input:    "(/ 6 2)",
+			expected: "3",
+		},
+		{
+			name:     "float modulo",
+			input:    "(modulo 5.5 2)",
+			expected: "1.5",
+		},
+		{
+			name:     "compare int and float",
+			input:    "(list (< 1 1.5) (> 1 1.5) (<= 2 2.0) (>= 2.5 3))",
+			expected: "(true false true false)",
+		},
+		{
+			name:     "ints and floats with the same value are equal",
+			input:    "(= 1 1.0)",
+			expected: "true",
+		},
+		{
+			name:     "ints and floats with the same value are the same hashmap key",
+			input:    `(hashmap-get {1 "a"} 1.0)`,
+			expected: `"a"`,
+		},
+		{
+			name:          "maths with non-numbers is an error",
+			input:         `(- 1 "a")`,
+			expectedError: errors.New("the function - expects the 2nd argument `\"a\"` to be a number, got type string"),
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestNumericConversions(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "float from int",
+			input:    "(float 1)",
+			expected: "1.0",
+		},
+		{
+			name:     "float from string",
+			input:    `(float "1.5")`,
+			expected: "1.5",
+		},
+		{
+			name:     "floor",
+			input:    "(list (floor 1.5) (floor -1.5) (floor 2))",
+			expected: "(1 -2 2)",
+		},
+		{
+			name:     "ceil",
+			input:    "(list (ceil 1.5) (ceil -1.5) (ceil 2))",
+			expected: "(2 -1 2)",
+		},
+		{
+			name:     "round",
+			input:    "(list (round 1.5) (round -1.5) (round 1.4))",
+			expected: "(2 -2 1)",
+		},
+		{
+			name:     "truncate",
+			input:    "(list (truncate 1.5) (truncate -1.5))",
+			expected: "(1 -1)",
+		},
+		{
+			name:     "int truncates floats",
+			input:    "(int -2.7)",
+			expected: "-2",
+		},
+		{
+			name:          "floor of infinity is an error",
+			input:         "(floor (/ 1.0 0))",
+			expectedError: errors.New("floor: can't convert +Inf to an int"),
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestBigInts(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "big int literal",
+			input:    "123456789012345678901234567890",
+			expected: "123456789012345678901234567890",
+		},
+		{
+			name:     "addition overflow promotes to a big int",
+			input:    "(+ 9223372036854775807 1)",
+			expected: "9223372036854775808",
+		},
+		{
+			name:     "subtraction overflow promotes to a big int",
+			input:    "(- -9223372036854775808 1)",
+			expected: "-9223372036854775809",
+		},
+		{
+			name:     "multiplication overflow promotes to a big int",
+			input:    "(* 9223372036854775807 2)",
+			expected: "18446744073709551614",
+		},
+		{
+			name:     "multiplying min int by -1 promotes to a big int",
+			input:    "(* -9223372036854775808 -1)",
+			expected: "9223372036854775808",
+		},
+		{
+			name:     "big int results which fit in an int are demoted",
+			input:    "(nth (list 1 2 3) (- (+ 9223372036854775807 1) 9223372036854775807))",
+			expected: "2",
+		},
+		{
+			name:     "exact big int division",
+			input:    "(/ 100000000000000000000 10)",
+			expected: "10000000000000000000",
+		},
+		{
+			name:     "big int modulo",
+			input:    "(modulo 100000000000000000001 10)",
+			expected: "1",
+		},
+		{
+			name:     "compare big ints and ints",
+			input:    "(list (< 1 100000000000000000000) (> 100000000000000000000 1.5))",
+			expected: "(true true)",
+		},
+		{
+			name:     "big ints and ints with the same value are equal",
+			input:    "(= (+ 9223372036854775807 1 -1) 9223372036854775807)",
+			expected: "true",
+		},
+		{
+			name:     "big ints can be hash map keys",
+			input:    "(hashmap-get (hashmap (+ 9223372036854775807 1) 1) 9223372036854775808)",
+			expected: "1",
+		},
+		{
+			name:     "big ints are converted to floats",
+			input:    "(+ 100000000000000000000 0.5)",
+			expected: "1e+20",
+		},
+		{
+			name:     "int parses big ints",
+			input:    `(int "100000000000000000000")`,
+			expected: "100000000000000000000",
+		},
+		{
+			name:     "large floats round to big ints",
+			input:    "(round 1e20)",
+			expected: "100000000000000000000",
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestRatios(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "ratio literal",
+			input:    "1/3",
+			expected: "1/3",
+		},
+		{
+			name:     "ratio literals are read in lowest terms",
+			input:    "-6/4",
+			expected: "-3/2",
+		},
+		{
+			name:     "integral ratio literals are read as ints",
+			input:    "(list 4/2 (+ 4/2 1))",
+			expected: "(2 3)",
+		},
+		{
+			name:     "inexact int division returns a ratio",
+			input:    "(/ 1 3)",
+			expected: "1/3",
+		},
+		{
+			name:     "inexact big int division returns a ratio",
+			input:    "(/ 100000000000000000000 3)",
+			expected: "100000000000000000000/3",
+		},
+		{
+			name:     "ratio arithmetic",
+			input:    "(list (+ 1/3 1/6) (- 1/2 1) (* 2/3 3/4) (/ 1/2 1/4))",
+			expected: "(1/2 -1/2 1/2 2)",
+		},
+		{
+			name:     "ratios and floats",
+			input:    "(+ 1/2 0.25)",
+			expected: "0.75",
+		},
+		{
+			name:     "ratio modulo",
+			input:    "(list (modulo 7/2 1) (modulo -7/2 1))",
+			expected: "(1/2 -1/2)",
+		},
+		{
+			name:     "compare ratios",
+			input:    "(list (< 1/3 1/2) (> 1/3 0.5) (<= 1/2 1) (>= 3/2 1))",
+			expected: "(true false true true)",
+		},
+		{
+			name:     "ratios equal to other numbers",
+			input:    "(list (= 1/2 0.5) (= (/ 2 4) 1/2) (= 1/3 1/2))",
+			expected: "(true true false)",
+		},
+		{
+			name:     "numerator and denominator",
+			input:    "(list (numerator 6/4) (denominator 6/4) (numerator 5) (denominator 5))",
+			expected: "(3 2 5 1)",
+		},
+		{
+			name:     "rationalize",
+			input:    "(list (rationalize 0.75) (rationalize 0.1) (rationalize 2.0) (rationalize 1/3))",
+			expected: "(3/4 1/10 2 1/3)",
+		},
+		{
+			name:     "float converts ratios",
+			input:    "(float 1/4)",
+			expected: "0.25",
+		},
+		{
+			name:     "rounding ratios",
+			input:    "(list (floor -7/2) (ceil -7/2) (round -7/2) (truncate -7/2) (round 7/3) (int 7/2))",
+			expected: "(-4 -3 -4 -3 2 3)",
+		},
+		{
+			name:     "ratios can be hash map keys",
+			input:    "(hashmap-get (hashmap 1/2 1) 0.5)",
+			expected: "1",
+		},
+		{
+			name:          "numerator of a float",
+			input:         "(numerator 0.5)",
+			expectedError: errors.New("numerator: expected an int or ratio, got type float"),
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestVariadicMaths(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "add with no arguments",
+			input:    "(+)",
+			expected: "0",
+		},
+		{
+			name:     "subtract many",
+			input:    "(- 10 2 3)",
+			expected: "5",
+		},
+		{
+			name:     "negate",
+			input:    "(list (- 1) (- -1.5) (- 1/2))",
+			expected: "(-1 1.5 -1/2)",
+		},
+		{
+			name:     "multiply many",
+			input:    "(* 1 2 3 4)",
+			expected: "24",
+		},
+		{
+			name:     "multiply with no arguments",
+			input:    "(*)",
+			expected: "1",
+		},
+		{
+			name:     "divide many",
+			input:    "(/ 12 2 3)",
+			expected: "2",
+		},
+		{
+			name:     "reciprocal",
+			input:    "(list (/ 2) (/ 0.5))",
+			expected: "(1/2 2.0)",
+		},
+		{
+			name:     "comparison chains",
+			input:    "(list (< 1 2 3) (< 1 3 2) (<= 1 1 2) (> 3 2 1) (>= 3 3 4) (< 1))",
+			expected: "(true false true true false true)",
+		},
+		{
+			name:     "equals many",
+			input:    "(list (= 1 1 1.0) (= 1 1 2) (= \"a\"))",
+			expected: "(true false true)",
+		},
+		{
+			name:          "subtract with no arguments",
+			input:         "(-)",
+			expectedError: errors.New("the function - expects at least 1 arguments, but got 0"),
+		},
+		{
+			name:          "comparison with a non-number",
+			input:         "(< 1 2 true)",
+			expectedError: errors.New("the function < expects the third argument `true` to be a number, got type boolean"),
+		},
+		{
+			name:          "divide by zero",
+			input:         "(/ 1 0)",
+			expectedError: errors.New("/: division by zero"),
+		},
+		{
+			name:          "divide ratio by zero",
+			input:         "(/ 1/2 2 0)",
+			expectedError: errors.New("/: division by zero"),
+		},
+		{
+			name:          "modulo by zero",
+			input:         "(modulo 5 0)",
+			expectedError: errors.New("modulo: division by zero"),
+		},
+		{
+			name:     "divide big int by zero",
+			input:    `(try (/ 100000000000000000000 0) (catch e (exception-message e)))`,
+			expected: `"/: division by zero"`,
+		},
+		{
+			name:     "big int modulo zero",
+			input:    `(try (modulo 100000000000000000000 0) (catch e (exception-message e)))`,
+			expected: `"modulo: division by zero"`,
+		},
+		{
+			name:     "division by zero can be caught",
+			input:    `(try (/ 1 0) (catch e (exception-message e)))`,
+			expected: `"/: division by zero"`,
+		},
+		{
+			name:     "float division by zero is infinite",
+			input:    "(/ 1.0 0)",
+			expected: "+Inf",
+		},
+	}
+	runTests(t, cases)
+}
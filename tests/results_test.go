@@ -0,0 +1,158 @@
+package sketchtest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResults(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "ok",
+			input:    "(ok 1)",
+			expected: "(ok 1)",
+		},
+		{
+			name:     "err",
+			input:    `(err "not found")`,
+			expected: `(err "not found")`,
+		},
+		{
+			name:     "ok? with ok",
+			input:    "(ok? (ok 1))",
+			expected: "true",
+		},
+		{
+			name:     "ok? with err",
+			input:    `(ok? (err "not found"))`,
+			expected: "false",
+		},
+		{
+			name:     "unwrap ok",
+			input:    "(unwrap (ok 1))",
+			expected: "1",
+		},
+		{
+			name:          "unwrap err raises an error",
+			input:         `(unwrap (err "not found"))`,
+			expectedError: errors.New(`unwrap: called with the error value (err "not found")`),
+		},
+		{
+			name:     "unwrap err raises an error with the value as its payload",
+			input:    `(try (unwrap (err "not found")) (catch e (exception-payload e)))`,
+			expected: `"not found"`,
+		},
+		{
+			name:     "unwrap-or ok",
+			input:    "(unwrap-or (ok 1) 2)",
+			expected: "1",
+		},
+		{
+			name:     "unwrap-or err",
+			input:    `(unwrap-or (err "not found") 2)`,
+			expected: "2",
+		},
+		{
+			name:     "and-then ok",
+			input:    `(and-then (ok "1") try-int)`,
+			expected: "(ok 1)",
+		},
+		{
+			name:     "and-then err",
+			input:    `(and-then (err "not found") try-int)`,
+			expected: `(err "not found")`,
+		},
+		{
+			name:     "and-then chained",
+			input:    `(list (and-then (try-hashmap-get {1 "2"} 1) try-int) (and-then (try-hashmap-get {1 "2"} 3) try-int))`,
+			expected: `((ok 2) (err "map doesn't contain key 3"))`,
+		},
+		{
+			name:     "results can be compared",
+			input:    "(= (ok 1) (ok 1))",
+			expected: "true",
+		},
+		{
+			name:          "passing an error result to a function which doesn't handle it is an error",
+			input:         `(+ 1 (err "not found"))`,
+			expectedError: errors.New(`the function + was passed the unhandled error value (err "not found") as its 2nd argument - handle it with ok?, unwrap or unwrap-or first`),
+		},
+		{
+			name:     "ok results aren't rejected",
+			input:    `(list (ok 1))`,
+			expected: "((ok 1))",
+		},
+	}
+	runTests(t, cases)
+}
+
+func TestResultVariants(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "try-nth in range",
+			input:    "(try-nth (list 1 2) 1)",
+			expected: "(ok 2)",
+		},
+		{
+			name:          "try-nth with too few arguments",
+			input:         "(try-nth (list 1))",
+			expectedError: errors.New("the function try-nth expects 2 arguments, but got 1"),
+		},
+		{
+			name:          "try-nth with no arguments",
+			input:         "(try-nth)",
+			expectedError: errors.New("the function try-nth expects 2 arguments, but got 0"),
+		},
+		{
+			name:     "try-nth out of range",
+			input:    "(try-nth (list 1 2) 2)",
+			expected: `(err "try-nth: index out of range - 2, with length 2")`,
+		},
+		{
+			name:     "try-hashmap-get found",
+			input:    "(try-hashmap-get {1 2} 1)",
+			expected: "(ok 2)",
+		},
+		{
+			name:     "try-hashmap-get not found",
+			input:    "(try-hashmap-get {1 2} 3)",
+			expected: `(err "map doesn't contain key 3")`,
+		},
+		{
+			name:     "try-int",
+			input:    `(try-int "12")`,
+			expected: "(ok 12)",
+		},
+		{
+			name:     "try-int truncates floats and ratios, like int",
+			input:    `(list (try-int 1.5) (try-int -3/2) (try-int 2) (try-int "x"))`,
+			expected: `((ok 1) (ok -1) (ok 2) (err "strconv.Atoi: parsing \"x\": invalid syntax"))`,
+		},
+		{
+			name:     "try-int returns an error result for floats which aren't finite",
+			input:    `(ok? (try-int (/ 1.0 0.0)))`,
+			expected: "false",
+		},
+		{
+			name:          "try-int with the wrong type is still an error",
+			input:         `(try-int (list))`,
+			expectedError: errors.New("try-int: unable to convert type list to an int"),
+		},
+		{
+			name:     "file.try-read-all with a missing file",
+			input:    `(ok? (file.try-read-all "/does/not/exist"))`,
+			expected: "false",
+		},
+		{
+			name:     "file.try-read-all with no arguments",
+			input:    `(try (file.try-read-all) (catch e (exception-message e)))`,
+			expected: `"the function try-read-all expects 1 arguments, but got 0"`,
+		},
+		{
+			name:     "file.try-read-lines with no arguments",
+			input:    `(try (file.try-read-lines) (catch e (exception-message e)))`,
+			expected: `"the function try-read-lines expects 1 arguments, but got 0"`,
+		},
+	}
+	runTestsWithImports(t, cases, "file")
+}
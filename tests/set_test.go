@@ -0,0 +1,102 @@
+package sketchtest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "set literal",
+			input:    "#{3 1 2 1}",
+			expected: "#{1 2 3}",
+		},
+		{
+			name:     "set literal items are evaluated",
+			input:    "#{(+ 1 1) 1}",
+			expected: "#{1 2}",
+		},
+		{
+			name:     "set",
+			input:    "(list (set (list 1 2 1)) (set [2 1]) (set #{1}))",
+			expected: "(#{1 2} #{1 2} #{1})",
+		},
+		{
+			name:     "set?",
+			input:    "(list (set? #{}) (set? {}) (set? (list)))",
+			expected: "(true false false)",
+		},
+		{
+			name:     "contains?",
+			input:    "(list (contains? #{1 2} 1) (contains? #{1 2} 3) (contains? #{1} 1.0) (contains? {1 2} 1))",
+			expected: "(true false true true)",
+		},
+		{
+			name:     "conj",
+			input:    "(conj #{1} 2 1)",
+			expected: "#{1 2}",
+		},
+		{
+			name:     "disj",
+			input:    "(disj #{1 2 3} 1 2 4)",
+			expected: "#{3}",
+		},
+		{
+			name:     "union",
+			input:    "(union #{1 2} #{2 3} #{4})",
+			expected: "#{1 2 3 4}",
+		},
+		{
+			name:     "intersection",
+			input:    "(intersection #{1 2 3} #{2 3 4} #{3 2})",
+			expected: "#{2 3}",
+		},
+		{
+			name:     "difference",
+			input:    "(difference #{1 2 3} #{2} #{3 4})",
+			expected: "#{1}",
+		},
+		{
+			name:     "subset?",
+			input:    "(list (subset? #{1} #{1 2}) (subset? #{1 3} #{1 2}) (subset? #{} #{}))",
+			expected: "(true false true)",
+		},
+		{
+			name:     "equality",
+			input:    "(list (= #{1 2} #{2 1}) (= #{1} #{1 2}) (= #{1} (list 1)))",
+			expected: "(true false false)",
+		},
+		{
+			name:     "count and empty?",
+			input:    "(list (count #{1 2}) (empty? #{}) (empty? #{1}))",
+			expected: "(2 true false)",
+		},
+		{
+			name:     "sets are sequences",
+			input:    "(map (fn (x) (* x 2)) #{2 1})",
+			expected: "(2 4)",
+		},
+		{
+			name:     "sets can be hash map keys",
+			input:    "(hashmap-get {#{1 2} 3} #{2 1})",
+			expected: "3",
+		},
+		{
+			name:     "dedupe",
+			input:    "(dedupe (list 3 1 3 2 1))",
+			expected: "(1 2 3)",
+		},
+		{
+			name:          "union of a non-set",
+			input:         "(union #{1} (list 2))",
+			expectedError: errors.New("the function union expects the 2nd argument `(2)` to be type set, got type list"),
+		},
+		{
+			name:          "set items must be hashable",
+			input:         "(set (list nil))",
+			expectedError: errors.New("set item nil has type nil - can't add this to a set"),
+		},
+	}
+	runTests(t, cases)
+}
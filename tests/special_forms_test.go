@@ -1,6 +1,9 @@
 package sketchtest
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestSpecialForm_Fn(t *testing.T) {
 	cases := []*TestCase{
@@ -62,6 +65,11 @@ func TestSpecialForm_Quote(t *testing.T) {
 			input:    "(quote (1 1 1))",
 			expected: "(1 1 1)",
 		},
+		{
+			name:     "'x is read as (quote x)",
+			input:    "(list 'a '(1 1) ''b)",
+			expected: "(a (1 1) (quote b))",
+		},
 	}
 	runTests(t, cases)
 }
@@ -122,3 +130,102 @@ func TestSpecialForm_Macroexpand(t *testing.T) {
 	}
 	runTests(t, cases)
 }
+
+func TestSpecialForm_Try(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "try returns the value of its body if there's no error",
+			input:    `(try 1 (catch e 2))`,
+			expected: "1",
+		},
+		{
+			name:     "catch handles errors raised with error",
+			input:    `(try (error "oh no") (catch e (exception-message e)))`,
+			expected: `"oh no"`,
+		},
+		{
+			name:     "catch exposes the error's payload",
+			input:    `(try (error "oh no" (list 1 2)) (catch e (exception-payload e)))`,
+			expected: "(1 2)",
+		},
+		{
+			name:     "catch handles errors raised by builtins",
+			input:    `(try (nth (list) 1) (catch e (exception-message e)))`,
+			expected: `"nth: index out of range - 1, with length 0, []"`,
+		},
+		{
+			name:     "catch handles errors raised by builtins - hashmap-get",
+			input:    `(try (hashmap-get {} 1) (catch e (exception-message e)))`,
+			expected: `"map doesn't contain key 1"`,
+		},
+		{
+			name:     "builtin errors have a nil payload",
+			input:    `(try (nth (list) 1) (catch e (exception-payload e)))`,
+			expected: "nil",
+		},
+		{
+			name: "catch exposes the call stack",
+			input: `
+(do
+	(def f (fn (x) (error "oh no")))
+	(try (f 1) (catch e (exception-stack e))))`,
+			expected: `({:function "f" :args (1) :file nil :line 4 :column 7 :tail-calls 0} {:function "error" :args ("oh no") :file nil :line 3 :column 17 :tail-calls 0})`,
+		},
+		{
+			name: "the call stack counts elided tail calls",
+			input: `
+(do
+	(def countdown (fn (n) (if (= n 0) (error "done") (countdown (- n 1)))))
+	(try (countdown 3) (catch e (map (fn (frame) (list (:function frame) (:args frame) (:tail-calls frame))) (exception-stack e)))))`,
+			expected: `(("countdown" (0) 3) ("error" ("done") 0))`,
+		},
+		{
+			name: "rethrowing an exception doesn't change its call stack",
+			input: `
+(do
+	(def f (fn () (error "oh no")))
+	(def caught (try (f) (catch e e)))
+	(try (throw caught) (catch e nil))
+	(map (fn (frame) (:function frame)) (exception-stack caught)))`,
+			expected: `("f" "error")`,
+		},
+		{
+			name:     "throw raises any value as the payload",
+			input:    `(try (throw {"a" 1}) (catch e (exception-payload e)))`,
+			expected: `{"a" 1}`,
+		},
+		{
+			name:     "throw rethrows caught exceptions",
+			input:    `(try (try (error "inner") (catch e (throw e))) (catch e (exception-message e)))`,
+			expected: `"inner"`,
+		},
+		{
+			name: "finally is evaluated after the body",
+			input: `
+(do
+	(def a 1)
+	(try (def a 2) (finally (def a (+ a 1))))
+	a)`,
+			expected: "3",
+		},
+		{
+			name: "finally is evaluated after catch",
+			input: `
+(do
+	(def a 1)
+	(list (try (error "oh no") (catch e a) (finally (def a 2))) a))`,
+			expected: "(1 2)",
+		},
+		{
+			name:          "errors propagate through finally",
+			input:         `(try (error "oh no") (finally 1))`,
+			expectedError: errors.New("oh no"),
+		},
+		{
+			name:          "the caught error isn't bound outside the catch clause",
+			input:         `(do (try (error "oh no") (catch e 1)) e)`,
+			expectedError: errors.New("`e` is undefined"),
+		},
+	}
+	runTests(t, cases)
+}
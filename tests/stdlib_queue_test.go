@@ -1,6 +1,9 @@
 package sketchtest
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestQueue(t *testing.T) {
 	cases := []*TestCase{
@@ -44,6 +47,11 @@ func TestQueue(t *testing.T) {
 			input:    "(queue.to-list (queue.new 1 2 3))",
 			expected: "(1 2 3)",
 		},
+		{
+			name:          "Head of an empty queue",
+			input:         "(queue.head (queue.new))",
+			expectedError: errors.New("Can't peek an empty queue"),
+		},
 	}
 
 	runTestsWithImports(t, cases, "queue")
@@ -79,6 +79,15 @@ func TestSpecialForm_Quasiquote(t *testing.T) {
 	(quasiquote ("hello" (unquote a))))`,
 			expected: `("hello" "world")`,
 		},
+		{
+			name: "quasiquote reader macros",
+			input: `
+(do
+	(def a "world")
+	(def b (list 2 3))
+	` + "`" + `(1 ~@b ~a ~(+ 1 1) 'c))`,
+			expected: `(1 2 3 "world" 2 (quote c))`,
+		},
 	}
 	runTests(t, cases)
 }
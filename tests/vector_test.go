@@ -0,0 +1,122 @@
+package sketchtest
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVector(t *testing.T) {
+	cases := []*TestCase{
+		{
+			name:     "vector literal",
+			input:    "[1 2 3]",
+			expected: "[1 2 3]",
+		},
+		{
+			name:     "vector literal items are evaluated",
+			input:    "[(+ 1 1) [3]]",
+			expected: "[2 [3]]",
+		},
+		{
+			name:     "vector",
+			input:    "(vector 1 2)",
+			expected: "[1 2]",
+		},
+		{
+			name:     "vector?",
+			input:    "(list (vector? [1]) (vector? (list 1)) (list? [1]))",
+			expected: "(true false false)",
+		},
+		{
+			name:     "nth",
+			input:    "(nth [1 2 3] 1)",
+			expected: "2",
+		},
+		{
+			name:          "nth out of range",
+			input:         "(nth [1 2 3] 3)",
+			expectedError: errors.New("nth: index out of range - 3, with length 3"),
+		},
+		{
+			name:     "count and length",
+			input:    "(list (count [1 2 3]) (length []))",
+			expected: "(3 0)",
+		},
+		{
+			name:     "empty?",
+			input:    "(list (empty? []) (empty? [1]))",
+			expected: "(true false)",
+		},
+		{
+			name:     "assoc",
+			input:    "(do (def v [1 2 3]) (list (assoc v 0 4) v))",
+			expected: "([4 2 3] [1 2 3])",
+		},
+		{
+			name:     "assoc at the end appends",
+			input:    "(assoc [1 2] 2 3)",
+			expected: "[1 2 3]",
+		},
+		{
+			name:     "assoc hashmap",
+			input:    "(assoc {} 1 2)",
+			expected: "{1 2}",
+		},
+		{
+			name:     "conj appends to vectors",
+			input:    "(conj [1 2] 3 4)",
+			expected: "[1 2 3 4]",
+		},
+		{
+			name:     "conj prepends to lists",
+			input:    "(conj (list 1 2) 3 4)",
+			expected: "(4 3 1 2)",
+		},
+		{
+			name:     "first and rest",
+			input:    "(list (first [1 2 3]) (rest [1 2 3]) (first []) (rest []))",
+			expected: "(1 [2 3] nil [])",
+		},
+		{
+			name:     "map returns a vector",
+			input:    "(map (fn (x) (* x 2)) [1 2 3])",
+			expected: "[2 4 6]",
+		},
+		{
+			name:     "filter returns a vector",
+			input:    "(filter (fn (x) (> x 1)) [1 2 3])",
+			expected: "[2 3]",
+		},
+		{
+			name:     "fold-left",
+			input:    "(fold-left + 0 [1 2 3])",
+			expected: "6",
+		},
+		{
+			name:     "concat",
+			input:    "(list (concat [1] (list 2) [3]) (concat (list 1) [2]))",
+			expected: "([1 2 3] (1 2))",
+		},
+		{
+			name:     "apply",
+			input:    "(apply + [1 2 3])",
+			expected: "6",
+		},
+		{
+			name:     "equality",
+			input:    "(list (= [1 [2]] [1 [2]]) (= [1 2] [1 3]) (= [1 2] (list 1 2)) (= [1 (list 2)] (list 1.0 [2])))",
+			expected: "(true false true true)",
+		},
+		{
+			name:     "vectors as hash map keys",
+			input:    "(hashmap-get {[1 2] 3} [1 2])",
+			expected: "3",
+		},
+		{
+			name:     "large vector",
+			input:    "(do (def v (fold-left conj [] (range 5000))) (list (count v) (nth v 4321) (nth (assoc v 4321 0) 4321)))",
+			expected: "(5000 4321 0)",
+		},
+	}
+	runTests(t, cases)
+}